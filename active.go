@@ -0,0 +1,73 @@
+package playsound
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SoundInfo — снимок состояния активного звука.
+type SoundInfo struct {
+	Done     chan struct{} // Канал звука, по которому им можно управлять
+	Source   string        // Путь к файлу или URL
+	Params   PlayParams    // Параметры, с которыми звук запущен
+	Group    string        // Группа звука из PlayParams.Group
+	State    SoundState
+	Position float64   // Слышимая позиция, секунд
+	Duration float64   // Длительность трека, секунд (0, если неизвестна)
+	Volume   float64   // Текущая громкость
+	Started  time.Time // Время запуска
+}
+
+// ListActive возвращает все активные звуки в порядке запуска.
+// Это снимок: к моменту использования звук мог уже завершиться.
+func ListActive() []SoundInfo {
+	activeMu.Lock()
+	list := make([]*soundController, 0, len(activeSounds))
+	dones := make(map[uint64]chan struct{}, len(activeSounds))
+	for done, sc := range activeSounds {
+		list = append(list, sc)
+		dones[sc.seq] = done
+	}
+	activeMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].seq < list[j].seq })
+
+	infos := make([]SoundInfo, 0, len(list))
+	for _, sc := range list {
+		infos = append(infos, sc.info(dones[sc.seq]))
+	}
+	return infos
+}
+
+// GetInfo возвращает снимок состояния одного звука.
+func GetInfo(done chan struct{}) (SoundInfo, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return SoundInfo{}, fmt.Errorf("sound not found")
+	}
+	return control.info(done), nil
+}
+
+// info собирает SoundInfo. Вызывается без activeMu: плеер и счётчик
+// позиции имеют собственные блокировки.
+func (sc *soundController) info(done chan struct{}) SoundInfo {
+	info := SoundInfo{
+		Done:    done,
+		Source:  sc.source,
+		Params:  sc.params,
+		Group:   sc.params.Group,
+		State:   sc.State(),
+		Started: sc.started,
+	}
+	if sc.player != nil {
+		info.Volume = sc.player.Volume()
+	}
+	if sc.tracker != nil {
+		info.Position = bytesToSeconds(sc.audiblePos(), sc.sampleRate)
+	}
+	if sc.totalBytes > 0 {
+		info.Duration = bytesToSeconds(sc.totalBytes, sc.sampleRate)
+	}
+	return info
+}
@@ -0,0 +1,48 @@
+package playsound
+
+import "testing"
+
+func TestListActive(t *testing.T) {
+	first, second := make(chan struct{}), make(chan struct{})
+
+	activeMu.Lock()
+	activeSounds[second] = &soundController{source: "b.wav", seq: 2, state: StatePaused, params: PlayParams{Group: "ui"}}
+	activeSounds[first] = &soundController{source: "a.mp3", seq: 1, state: StatePlaying, sampleRate: 44100, totalBytes: 44100 * 4 * 3, params: PlayParams{Group: "music"}}
+	activeMu.Unlock()
+	defer func() {
+		activeMu.Lock()
+		delete(activeSounds, first)
+		delete(activeSounds, second)
+		activeMu.Unlock()
+	}()
+
+	list := ListActive()
+	if len(list) != 2 {
+		t.Fatalf("ListActive() returned %d sounds; want 2", len(list))
+	}
+	if list[0].Done != first || list[0].Source != "a.mp3" || list[0].Group != "music" {
+		t.Errorf("list[0] = %+v", list[0])
+	}
+	if list[0].State != StatePlaying || list[0].Duration != 3 {
+		t.Errorf("list[0]: state %v, duration %v; want playing, 3", list[0].State, list[0].Duration)
+	}
+	if list[1].Done != second || list[1].State != StatePaused {
+		t.Errorf("list[1] = %+v; want paused b.wav", list[1])
+	}
+
+	info, err := GetInfo(second)
+	if err != nil || info.Source != "b.wav" {
+		t.Errorf("GetInfo() = %+v, %v", info, err)
+	}
+	if _, err := GetInfo(make(chan struct{})); err == nil {
+		t.Error("GetInfo() for unknown sound should fail")
+	}
+}
+
+func TestSoundStateString(t *testing.T) {
+	for state, want := range map[SoundState]string{StateIdle: "idle", StatePlaying: "playing", StateFading: "fading", StatePaused: "paused", StateStopped: "stopped"} {
+		if got := state.String(); got != want {
+			t.Errorf("%d.String() = %q; want %q", state, got, want)
+		}
+	}
+}
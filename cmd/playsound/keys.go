@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// key — команда, полученная с клавиатуры.
+type key int
+
+const (
+	keyPause key = iota
+	keyForward
+	keyBack
+	keyVolumeUp
+	keyVolumeDown
+	keyNext
+	keyQuit
+)
+
+// terminal хранит исходные настройки терминала, чтобы вернуть их при выходе.
+type terminal struct {
+	saved string
+}
+
+// enableRawMode переводит терминал в посимвольный режим без эха через stty,
+// чтобы клавиши срабатывали без Enter. Если stdin не терминал или stty
+// недоступен (например, в Windows), команды нужно подтверждать Enter.
+func enableRawMode() *terminal {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return &terminal{}
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return &terminal{}
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return &terminal{}
+	}
+	return &terminal{saved: strings.TrimSpace(saved)}
+}
+
+// restore возвращает терминал в исходный режим.
+func (t *terminal) restore() {
+	if t.saved != "" {
+		stty(t.saved)
+	}
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys читает stdin в фоне и переводит нажатия в команды.
+// Стрелки приходят escape-последовательностями ESC [ A..D.
+func readKeys() <-chan key {
+	keys := make(chan key)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i < n; i++ {
+				var k key
+				switch c := buf[i]; {
+				case c == 0x1b && i+2 < n && buf[i+1] == '[':
+					i += 2
+					switch buf[i] {
+					case 'A':
+						k = keyVolumeUp
+					case 'B':
+						k = keyVolumeDown
+					case 'C':
+						k = keyForward
+					case 'D':
+						k = keyBack
+					default:
+						continue
+					}
+				case c == ' ' || c == 'p':
+					k = keyPause
+				case c == '.' || c == 'l':
+					k = keyForward
+				case c == ',' || c == 'h':
+					k = keyBack
+				case c == '+' || c == '=':
+					k = keyVolumeUp
+				case c == '-':
+					k = keyVolumeDown
+				case c == 'n':
+					k = keyNext
+				case c == 'q':
+					k = keyQuit
+				default:
+					continue
+				}
+				keys <- k
+			}
+		}
+	}()
+	return keys
+}
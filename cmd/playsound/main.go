@@ -0,0 +1,340 @@
+// Команда playsound проигрывает файлы, URL и плейлисты M3U через библиотеку playsound.
+//
+// Использование:
+//
+//	playsound [флаги] файл|URL|плейлист.m3u ...
+//
+// Во время воспроизведения: пробел — пауза, ←/→ — перемотка на 10 секунд,
+// ↑/↓ — громкость, n — следующий трек, q — выход.
+// С флагом -render звук не выводится на устройство, а записывается в WAV-файл,
+// поэтому команда работает и на машинах без звуковой карты.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Roman77St/playsound"
+)
+
+const (
+	seekStep   = 10.0                  // Шаг перемотки, секунд
+	seekFade   = 20 * time.Millisecond // Затухание на стыке перемотки, чтобы она не щёлкала
+	volumeStep = 0.1                   // Шаг изменения громкости
+)
+
+// options — значения флагов командной строки.
+type options struct {
+	params     playsound.PlayParams
+	spatial    string
+	lowPass    float64
+	highPass   float64
+	reverb     float64
+	delay      time.Duration
+	render     string
+	renderRate int
+}
+
+func main() {
+	opts := parseFlags()
+
+	tracks, err := expandPlaylists(flag.Args())
+	if err != nil {
+		fatal(err)
+	}
+	if len(tracks) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if opts.render != "" {
+		if err := render(tracks, opts); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if err := play(tracks, opts.params); err != nil {
+		fatal(err)
+	}
+}
+
+func parseFlags() *options {
+	opts := &options{}
+	p := &opts.params
+
+	flag.Float64Var(&p.Volume, "volume", 1, "громкость 0..1")
+	flag.BoolVar(&p.Loop, "loop", false, "зациклить трек")
+	flag.BoolVar(&p.FadeIn, "fadein", false, "плавный старт")
+	flag.BoolVar(&p.FadeOut, "fadeout", false, "плавное затухание при паузе и остановке")
+	flag.DurationVar(&p.FadeInTime, "fadein-time", 0, "длительность плавного старта (по умолчанию 1.5s)")
+	flag.DurationVar(&p.FadeOutTime, "fadeout-time", 0, "длительность затухания (по умолчанию 1s)")
+	flag.Float64Var(&p.Position, "position", 0, "начать с указанной секунды")
+	flag.Float64Var(&p.Speed, "speed", 1, "скорость воспроизведения (0.25..4)")
+	flag.Float64Var(&p.Pitch, "pitch", 0, "сдвиг тона в полутонах")
+	flag.BoolVar(&p.PreservePitch, "preserve-pitch", false, "сохранять тон при изменении скорости")
+	flag.BoolVar(&p.Reverse, "reverse", false, "играть задом наперёд")
+	flag.BoolVar(&p.Normalize, "normalize", false, "выравнивать громкость треков")
+	flag.Float64Var(&p.TargetLUFS, "target-lufs", 0, "целевая громкость нормализации (по умолчанию -18)")
+	flag.Float64Var(&p.Pan, "pan", 0, "стереопанорама -1..1")
+	flag.StringVar(&p.Group, "group", "", "группа звука для лимитов голосов")
+	flag.IntVar(&p.Priority, "priority", 0, "приоритет при вытеснении голосов")
+	flag.StringVar(&opts.spatial, "spatial", "", "положение источника в 3D `x,y,z`")
+	flag.Float64Var(&opts.lowPass, "lowpass", 0, "фильтр НЧ с частотой среза, Гц")
+	flag.Float64Var(&opts.highPass, "highpass", 0, "фильтр ВЧ с частотой среза, Гц")
+	flag.Float64Var(&opts.reverb, "reverb", 0, "реверберация: размер комнаты 0..1")
+	flag.DurationVar(&opts.delay, "delay", 0, "эхо с указанной задержкой (например, 300ms)")
+	flag.StringVar(&opts.render, "render", "", "записать результат в WAV `файл` вместо вывода на устройство")
+	flag.IntVar(&opts.renderRate, "rate", 44100, "частота дискретизации для -render")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Использование: %s [флаги] файл|URL|плейлист.m3u ...\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// В библиотеке тишина задаётся как -1, а 0 означает громкость по умолчанию
+	if p.Volume == 0 {
+		p.Volume = -1
+	}
+
+	if opts.spatial != "" {
+		pos, err := parseVec3(opts.spatial)
+		if err != nil {
+			fatal(err)
+		}
+		p.Spatial = &playsound.SpatialParams{Position: pos}
+	}
+	if opts.highPass > 0 {
+		p.Effects = append(p.Effects, playsound.NewHighPass(opts.highPass))
+	}
+	if opts.lowPass > 0 {
+		p.Effects = append(p.Effects, playsound.NewLowPass(opts.lowPass))
+	}
+	if opts.delay > 0 {
+		p.Effects = append(p.Effects, playsound.NewDelay(playsound.DelayParams{Time: opts.delay, Feedback: 0.4, Mix: 0.3}))
+	}
+	if opts.reverb > 0 {
+		p.Effects = append(p.Effects, playsound.NewReverb(playsound.ReverbParams{RoomSize: opts.reverb, Damping: 0.5, Wet: 0.3}))
+	}
+	return opts
+}
+
+// parseVec3 разбирает координаты вида "x,y,z".
+func parseVec3(s string) (playsound.Vec3, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return playsound.Vec3{}, fmt.Errorf("invalid position %q: want x,y,z", s)
+	}
+	var v [3]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return playsound.Vec3{}, fmt.Errorf("invalid position %q: %v", s, err)
+		}
+		v[i] = f
+	}
+	return playsound.Vec3{X: v[0], Y: v[1], Z: v[2]}, nil
+}
+
+// expandPlaylists заменяет плейлисты M3U их содержимым.
+// Относительные пути в плейлисте отсчитываются от его каталога.
+func expandPlaylists(args []string) ([]string, error) {
+	var tracks []string
+	for _, arg := range args {
+		ext := strings.ToLower(filepath.Ext(arg))
+		if isURL(arg) || (ext != ".m3u" && ext != ".m3u8") {
+			tracks = append(tracks, arg)
+			continue
+		}
+
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !isURL(line) && !filepath.IsAbs(line) {
+				line = filepath.Join(filepath.Dir(arg), line)
+			}
+			tracks = append(tracks, line)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tracks, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// render записывает все треки подряд в один WAV-файл.
+func render(tracks []string, opts *options) error {
+	f, err := os.Create(opts.render)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := playsound.NewRenderer(f, opts.renderRate)
+	if err != nil {
+		return err
+	}
+	for _, track := range tracks {
+		start := r.Duration()
+		if err := r.Render(track, opts.params); err != nil {
+			return fmt.Errorf("%s: %v", track, err)
+		}
+		fmt.Printf("%s: %s\n", track, formatTime((r.Duration() - start).Seconds()))
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Записано в %s: %s\n", opts.render, formatTime(r.Duration().Seconds()))
+	return f.Close()
+}
+
+// play проигрывает треки по очереди и обрабатывает клавиши управления.
+func play(tracks []string, params playsound.PlayParams) error {
+	term := enableRawMode()
+	defer term.restore()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	keys := readKeys()
+
+	for i, track := range tracks {
+		done, err := playsound.PlaySoundWithParams(track, params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\r%s: %v\n", track, err)
+			continue
+		}
+		fmt.Printf("\r\033[K[%d/%d] %s\n", i+1, len(tracks), trackTitle(done, track))
+
+		switch control(done, params, keys, interrupt) {
+		case actionQuit:
+			playsound.StopAll()
+			<-done
+			fmt.Println()
+			return nil
+		case actionNext:
+			playsound.Stop(done)
+			<-done
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// trackTitle возвращает «Исполнитель — Название» из тегов или имя файла.
+func trackTitle(done chan struct{}, track string) string {
+	tags, _, err := playsound.GetTags(done)
+	if err == nil && tags.Title() != "" {
+		if tags.Artist() != "" {
+			return tags.Artist() + " — " + tags.Title()
+		}
+		return tags.Title()
+	}
+	return filepath.Base(track)
+}
+
+type action int
+
+const (
+	actionEnded action = iota // Трек доиграл до конца
+	actionNext
+	actionQuit
+)
+
+// control обрабатывает клавиши и обновляет строку состояния, пока трек играет.
+func control(done chan struct{}, params playsound.PlayParams, keys <-chan key, interrupt <-chan os.Signal) action {
+	volume := max(params.Volume, 0)
+	paused := false
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return actionEnded
+		case <-interrupt:
+			return actionQuit
+		case <-ticker.C:
+		case k := <-keys:
+			switch k {
+			case keyQuit:
+				return actionQuit
+			case keyNext:
+				return actionNext
+			case keyPause:
+				// Переключение может не состояться (например, трек как раз
+				// закончился) — тогда индикатор и следующее нажатие не меняются
+				toggle := playsound.Pause
+				if paused {
+					toggle = playsound.PlayOn
+				}
+				if toggle(done) == nil {
+					paused = !paused
+				}
+			case keyForward, keyBack:
+				step := seekStep
+				if k == keyBack {
+					step = -seekStep
+				}
+				playsound.SeekWithOptions(done, step, playsound.SeekOptions{Relative: true, Fade: seekFade})
+			case keyVolumeUp, keyVolumeDown:
+				if k == keyVolumeUp {
+					volume = min(volume+volumeStep, 1)
+				} else {
+					volume = max(volume-volumeStep, 0)
+				}
+				playsound.SetVolume(done, volume)
+			}
+		}
+		printStatus(done, volume, paused)
+	}
+}
+
+// printStatus перерисовывает строку с позицией, длительностью и громкостью.
+func printStatus(done chan struct{}, volume float64, paused bool) {
+	pos, err := playsound.GetPosition(done)
+	if err != nil {
+		return
+	}
+	dur, _ := playsound.GetDuration(done)
+
+	state := "▶"
+	if paused {
+		state = "⏸"
+	}
+	fmt.Printf("\r\033[K%s %s / %s  громкость %3.0f%%", state, formatTime(pos), formatTime(dur), volume*100)
+}
+
+// formatTime форматирует секунды как мм:сс (или ч:мм:сс).
+func formatTime(seconds float64) string {
+	s := int(max(seconds, 0))
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "playsound:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Roman77St/playsound"
+)
+
+func TestExpandPlaylists(t *testing.T) {
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "list.m3u")
+	content := "\ufeff#EXTM3U\n#EXTINF:10,Song\nsong.mp3\n\n/abs/track.wav\nhttps://example.com/a.mp3\n"
+	if err := os.WriteFile(playlist, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := expandPlaylists([]string{"first.wav", playlist})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"first.wav", filepath.Join(dir, "song.mp3"), "/abs/track.wav", "https://example.com/a.mp3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandPlaylists() = %q; want %q", got, want)
+	}
+}
+
+func TestParseVec3(t *testing.T) {
+	v, err := parseVec3("1, -2.5,3")
+	if err != nil || v != (playsound.Vec3{X: 1, Y: -2.5, Z: 3}) {
+		t.Errorf("parseVec3() = %v, %v", v, err)
+	}
+	if _, err := parseVec3("1,2"); err == nil {
+		t.Error("expected error for two coordinates")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	for seconds, want := range map[float64]string{0: "00:00", 75.9: "01:15", 3725: "1:02:05", -3: "00:00"} {
+		if got := formatTime(seconds); got != want {
+			t.Errorf("formatTime(%v) = %q; want %q", seconds, got, want)
+		}
+	}
+}
@@ -1,29 +1,122 @@
 package playsound
 
 import (
-	"context"
 	"fmt"
-	"io"
+	"math"
+	"time"
 )
 
-// StopAll мгновенно останавливает все проигрываемые в данный момент звуки.
+// TransitionOptions настраивает паузу и продолжение (PauseWithOptions, PlayOnWithOptions).
+type TransitionOptions struct {
+	Fade   time.Duration // Длительность плавности (0 — по параметрам звука: FadeOut/FadeOutTime для паузы, FadeIn/FadeInTime для продолжения)
+	NoFade bool          // Переключить мгновенно, даже если плавность включена в параметрах звука
+	Wait   bool          // Вернуться только после окончания плавности
+	OnDone func(ok bool) // Вызывается в отдельной горутине, когда звук дошёл до нового состояния (ok) или переход прервали
+}
+
+// done сообщает OnDone об окончании перехода.
+func (o TransitionOptions) done(ok bool) {
+	if o.OnDone != nil {
+		go o.OnDone(ok)
+	}
+}
+
+// fadeTime возвращает длительность плавности перехода и нужна ли она.
+// enabled и d — настройки плавности из параметров звука.
+func (o TransitionOptions) fadeTime(enabled bool, d time.Duration) (time.Duration, bool) {
+	switch {
+	case o.NoFade:
+		return 0, false
+	case o.Fade > 0:
+		return o.Fade, true
+	}
+	return d, enabled
+}
+
+// StopAll останавливает все проигрываемые в данный момент звуки. Звуки с
+// FadeOut затухают, как при Stop. Звуки, запуск которых начался до вызова,
+// но ещё не завершился, тоже не зазвучат: их запуск вернёт ошибку.
 func StopAll() {
-	mu.Lock()
-	defer mu.Unlock()
-	if rootCancel != nil {
-		rootCancel()
-		rootCtx, rootCancel = context.WithCancel(context.Background())
+	for _, control := range stopAllControls() {
+		control.stop(control.defaultStopFade())
+	}
+}
+
+// StopAllWithOptions останавливает все звуки с затуханием длительностью fade
+// (0 — сразу). С wait функция возвращается, когда все звуки остановлены.
+func StopAllWithOptions(fade time.Duration, wait bool) {
+	controls := stopAllControls()
+	for _, control := range controls {
+		control.stop(fade)
+	}
+	if wait {
+		for done := range controls {
+			<-done
+		}
 	}
 }
 
-// Stop останавливает конкретный звук по его каналу done
+// stopAllControls отмечает вызов StopAll и возвращает звуки, которые нужно остановить.
+func stopAllControls() map[chan struct{}]*soundController {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	stopEpoch++
+	controls := make(map[chan struct{}]*soundController, len(activeSounds))
+	for done, control := range activeSounds {
+		controls[done] = control
+	}
+	return controls
+}
+
+// Stop останавливает конкретный звук по его каналу done.
+// Со звуком, запущенным с FadeOut, он сначала затихает за FadeOutTime.
 func Stop(done chan struct{}) {
 	control, ok := getControl(done)
 	if ok {
-		control.cancel()
+		control.stop(control.defaultStopFade())
 	}
 }
 
+// StopWithOptions останавливает звук с затуханием длительностью fade
+// (0 — сразу), независимо от FadeOut в параметрах звука. Остановка без
+// затухания прерывает уже идущее затухание. С wait функция возвращается,
+// когда звук остановлен и его ресурсы освобождены.
+func StopWithOptions(done chan struct{}, fade time.Duration, wait bool) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+	if fade < 0 {
+		return fmt.Errorf("invalid fade %v", fade)
+	}
+
+	control.stop(fade)
+	if wait {
+		<-done
+	}
+	return nil
+}
+
+// defaultStopFade возвращает затухание при остановке из параметров звука.
+func (sc *soundController) defaultStopFade() time.Duration {
+	if sc.params.FadeOut {
+		return sc.params.FadeOutTime
+	}
+	return 0
+}
+
+// stop останавливает звук с затуханием длительностью fade или сразу.
+// Само затухание и освобождение ресурсов выполняет monitorPlayback.
+func (sc *soundController) stop(fade time.Duration) {
+	if fade <= 0 {
+		sc.stopped()
+	}
+	sc.mu.Lock()
+	sc.stopFade = fade
+	sc.mu.Unlock()
+	sc.cancel()
+}
+
 // SetVolume динамически меняет громкость уже играющего звука.
 // Возвращает ошибку, если звук не найден (уже завершился).
 func SetVolume(done chan struct{}, volume float64) error {
@@ -33,11 +126,12 @@ func SetVolume(done chan struct{}, volume float64) error {
 		return fmt.Errorf("sound already finished or not found")
 	}
 
-	control.player.SetVolume(volume)
+	control.setVolume(volume)
 	return nil
 }
 
-// GetVolume возвращает текущую громкость звука.
+// GetVolume возвращает громкость звука, заданную при запуске или через SetVolume.
+// Во время плавности это громкость, к которой звук придёт, а не текущий шаг.
 func GetVolume(done chan struct{}) (float64, error) {
 	control, ok := getControl(done)
 
@@ -45,10 +139,26 @@ func GetVolume(done chan struct{}) (float64, error) {
 		return 0, fmt.Errorf("sound already finished or not found")
 	}
 
-	return control.player.Volume(), nil
+	control.mu.Lock()
+	defer control.mu.Unlock()
+	return control.volume, nil
+}
+
+// setVolume запоминает громкость пользователя. Во время плавности голос не
+// трогаем: нарастание само придёт к новой громкости, а после затухания звук
+// вернётся к ней при продолжении.
+func (sc *soundController) setVolume(volume float64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.volume = volume
+	if sc.state != StateFading {
+		sc.player.SetVolume(volume)
+	}
 }
 
-// Возвращает текущую позицию трека в секундах
+// GetPosition возвращает позицию трека в секундах, которую сейчас слышит слушатель.
+// Из прочитанного декодером объёма вычитаются данные, ожидающие в буфере плеера,
+// и задержка устройства вывода (см. SetOutputLatency).
 func GetPosition(done chan struct{}) (float64, error) {
 	control, ok := getControl(done)
 
@@ -56,65 +166,210 @@ func GetPosition(done chan struct{}) (float64, error) {
 		return 0, fmt.Errorf("sound not found")
 	}
 
-	pos := control.tracker.CurrentPos()
+	return bytesToSeconds(control.audiblePos(), control.sampleRate), nil
+}
+
+// GetDecodedPosition возвращает позицию, до которой трек уже декодирован.
+// Она опережает GetPosition на размер буферов и годится для подготовки данных заранее.
+func GetDecodedPosition(done chan struct{}) (float64, error) {
+	control, ok := getControl(done)
 
-	return bytesToSeconds(pos, control.sampleRate), nil
+	if !ok {
+		return 0, fmt.Errorf("sound not found")
+	}
+
+	return bytesToSeconds(control.tracker.CurrentPos(), control.sampleRate), nil
+}
+
+// SeekOptions настраивает перемотку (SeekWithOptions).
+type SeekOptions struct {
+	Relative bool          // Позиция отсчитывается от текущей слышимой (отрицательная — назад)
+	Fade     time.Duration // Затухание до и нарастание после перемотки, чтобы стык не щёлкал (0 — мгновенно)
 }
 
-// Перемотка запущенного трека.
+// Перемотка запущенного трека. Позиция ограничивается началом и концом трека.
 func Seek(done chan struct{}, seconds float64) error {
+	_, err := SeekWithOptions(done, seconds, SeekOptions{})
+	return err
+}
+
+// SeekBy перематывает звук на delta секунд вперёд (или назад при отрицательной
+// delta) от слышимой позиции и возвращает позицию, к которой он перемотан.
+func SeekBy(done chan struct{}, delta float64) (float64, error) {
+	return SeekWithOptions(done, delta, SeekOptions{Relative: true})
+}
+
+// SeekWithOptions перематывает звук и возвращает позицию в секундах, к которой
+// он перемотан: позиция округляется до кадра и ограничивается началом и концом
+// трека (если длина известна). С opts.Fade позиция применяется, когда звук
+// затихнет, но возвращается сразу.
+func SeekWithOptions(done chan struct{}, seconds float64, opts SeekOptions) (float64, error) {
 	control, ok := getControl(done)
 
 	if !ok {
-		return fmt.Errorf("звук не найден")
+		return 0, fmt.Errorf("sound not found")
+	}
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, fmt.Errorf("invalid position %v", seconds)
+	}
+
+	if opts.Relative {
+		seconds += bytesToSeconds(control.seekBase(), control.sampleRate)
 	}
+	// Ограничиваем до перевода в целое, чтобы огромная позиция не переполнила int64
+	frame := min(max(math.Round(seconds*float64(control.sampleRate)), 0), math.MaxInt64/frameBytes)
+	pos, err := control.seekFrame(int64(frame), opts.Fade)
+	return float64(pos) / float64(control.sampleRate), err
+}
 
-	offset := secondsToBytes(seconds, control.sampleRate)
+// SeekFrame перематывает звук к кадру frame (кадр — один семпл всех каналов
+// на частоте дискретизации файла) и возвращает кадр, к которому он перемотан.
+func SeekFrame(done chan struct{}, frame int64) (int64, error) {
+	control, ok := getControl(done)
 
-	_, err := control.tracker.Seek(offset, io.SeekStart)
-	return err
+	if !ok {
+		return 0, fmt.Errorf("sound not found")
+	}
+
+	return control.seekFrame(frame, 0)
+}
+
+// seekFrame перематывает звук к кадру frame, ограниченному началом и концом трека.
+func (sc *soundController) seekFrame(frame int64, fade time.Duration) (int64, error) {
+	frame = max(frame, 0)
+	if sc.totalBytes > 0 {
+		frame = min(frame, sc.totalBytes/frameBytes)
+	}
+	fadeFrames := int(fade.Seconds() * float64(sc.tracker.outputRate()))
+	pos, err := sc.player.seekSmooth(frame*frameBytes, fadeFrames)
+	return pos / frameBytes, err
 }
 
-// Pause приостанавливает воспроизведение
+// seekBase возвращает позицию, от которой отсчитывается относительная
+// перемотка: ещё не применённую цель предыдущей перемотки или слышимую позицию.
+func (sc *soundController) seekBase() int64 {
+	if target, ok := sc.player.pendingSeek(); ok {
+		return target
+	}
+	return sc.audiblePos()
+}
+
+// Pause приостанавливает воспроизведение. С FadeOut звук сначала плавно
+// затихает в фоне: функция возвращается сразу, а на паузу звук встаёт по
+// окончании затухания. PlayOn во время затухания разворачивает громкость
+// обратно. Для звука, который уже стоит на паузе, затухает перед ней или
+// остановлен, возвращается *TransitionError.
 func Pause(done chan struct{}) error {
+	return PauseWithOptions(done, TransitionOptions{})
+}
+
+// PauseWithOptions приостанавливает воспроизведение с заданной плавностью.
+// С opts.Wait функция дожидается конца затухания и возвращает *TransitionError,
+// если его прервали (PlayOn, Stop).
+func PauseWithOptions(done chan struct{}, opts TransitionOptions) error {
 	control, ok := getControl(done)
 
 	if !ok {
 		return fmt.Errorf("sound not found")
 	}
 
-	if control.params.FadeOut {
-		fadeOut(control.player)
-	}
-
-	control.player.Pause()
-	control.updateStatus(done, true)
-	return nil
+	return control.pause(opts)
 }
 
-// Resume возобновляет приостановленное воспроизведение
+// PlayOn возобновляет приостановленное воспроизведение. С FadeIn громкость
+// нарастает в фоне; во время затухания перед паузой PlayOn отменяет паузу и
+// возвращает громкость с текущего уровня. Если звук уже играет или
+// нарастает, возвращается *TransitionError.
 func PlayOn(done chan struct{}) error {
+	return PlayOnWithOptions(done, TransitionOptions{})
+}
+
+// PlayOnWithOptions возобновляет воспроизведение с заданной плавностью.
+// С opts.Wait функция дожидается конца нарастания и возвращает *TransitionError,
+// если его прервали (Pause, Stop).
+func PlayOnWithOptions(done chan struct{}, opts TransitionOptions) error {
 	control, ok := getControl(done)
 
 	if !ok {
 		return fmt.Errorf("sound not found")
 	}
 
-	// Подготовка громкости перед запуском
-	targetVol := control.params.Volume
-	if control.params.FadeIn {
-		control.player.SetVolume(0)
-	} else {
-		control.player.SetVolume(targetVol)
+	return control.play(opts)
+}
+
+// pause ставит звук на паузу сразу или после затухания.
+func (sc *soundController) pause(opts TransitionOptions) error {
+	d, fade := opts.fadeTime(sc.params.FadeOut, sc.params.FadeOutTime)
+	if !fade {
+		_, err := sc.switchTo(StatePaused, 0, sc.player.Pause)
+		if err == nil {
+			opts.done(true)
+		}
+		return err
+	}
+
+	gen, err := sc.switchTo(StateFading, StatePaused, nil)
+	if err != nil {
+		return err
+	}
+	return sc.runFade(gen, d, sc.player.Pause, StatePaused, opts)
+}
+
+// play запускает звук или снимает его с паузы: сразу с громкостью
+// пользователя или с плавным нарастанием к ней. Звук на паузе нарастает
+// с нуля, а прерванное затухание разворачивается с текущей громкости.
+func (sc *soundController) play(opts TransitionOptions) error {
+	d, fade := opts.fadeTime(sc.params.FadeIn, sc.params.FadeInTime)
+	if !fade {
+		_, err := sc.switchTo(StatePlaying, 0, func() {
+			sc.player.SetVolume(sc.volume)
+			sc.player.Play()
+		})
+		if err == nil {
+			opts.done(true)
+		}
+		return err
 	}
 
-	control.player.Play()
+	gen, err := sc.switchTo(StateFading, StatePlaying, func() {
+		if !sc.player.IsPlaying() {
+			sc.player.SetVolume(0)
+		}
+		sc.player.Play()
+	})
+	if err != nil {
+		return err
+	}
+	return sc.runFade(gen, d, nil, StatePlaying, opts)
+}
 
-	// Снимаем флаг паузы до запуска горутины, чтобы мониторинг не закрыл трек
-	control.updateStatus(done, false)
+// runFade запускает плавность перехода gen к состоянию to и, если opts.Wait,
+// дожидается её окончания.
+func (sc *soundController) runFade(gen uint64, d time.Duration, apply func(), to SoundState, opts TransitionOptions) error {
+	if !opts.Wait {
+		sc.fade(gen, d, apply, opts.done)
+		return nil
+	}
 
-	if control.params.FadeIn {
-		go fadeIn(control.player, control.params.Volume)
+	finished := make(chan bool, 1)
+	sc.fade(gen, d, apply, func(ok bool) {
+		opts.done(ok)
+		finished <- ok
+	})
+	if !<-finished {
+		return &TransitionError{From: sc.State(), To: to}
 	}
 	return nil
 }
+
+// SetReverse переключает направление воспроизведения звука.
+// Позиция сохраняется: трек продолжит звучать из той же точки в новом направлении.
+func SetReverse(done chan struct{}, reverse bool) error {
+	control, ok := getControl(done)
+
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	return control.tracker.setReverse(reverse)
+}
@@ -0,0 +1,167 @@
+package playsound
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// cueEventsBuffer — размер буфера канала событий меток.
+// Если получатель не успевает читать, лишние события отбрасываются, чтобы не блокировать звук.
+const cueEventsBuffer = 16
+
+// CueEvent описывает срабатывание метки на треке.
+type CueEvent struct {
+	ID       int     // Идентификатор метки, который вернул AddCue.
+	Name     string  // Произвольное имя метки.
+	Position float64 // Позиция метки в секундах от начала трека.
+}
+
+// cuePoint — одна зарегистрированная метка.
+type cuePoint struct {
+	id       int
+	name     string
+	offset   int64 // Смещение метки в байтах декодированного потока.
+	seconds  float64
+	callback func(CueEvent)
+}
+
+// cueList хранит метки одного звука, отсортированные по смещению.
+// Метка срабатывает, когда позиция чтения пересекает её смещение,
+// поэтому после перемотки назад или нового круга Loop она сработает снова,
+// а при перемотке вперёд через метку — не сработает.
+type cueList struct {
+	mu     sync.Mutex
+	nextID int
+	points []*cuePoint
+	events chan CueEvent
+	closed bool
+}
+
+// add регистрирует новую метку и возвращает её идентификатор.
+func (cl *cueList) add(offset int64, seconds float64, name string, callback func(CueEvent)) int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.nextID++
+	cp := &cuePoint{id: cl.nextID, name: name, offset: offset, seconds: seconds, callback: callback}
+	i := sort.Search(len(cl.points), func(i int) bool { return cl.points[i].offset > offset })
+	cl.points = append(cl.points, nil)
+	copy(cl.points[i+1:], cl.points[i:])
+	cl.points[i] = cp
+	return cp.id
+}
+
+// remove удаляет метку по идентификатору.
+func (cl *cueList) remove(id int) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	for i, cp := range cl.points {
+		if cp.id == id {
+			cl.points = append(cl.points[:i], cl.points[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe возвращает канал событий, создавая его при первом обращении.
+func (cl *cueList) subscribe() <-chan CueEvent {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.events == nil {
+		cl.events = make(chan CueEvent, cueEventsBuffer)
+		if cl.closed {
+			close(cl.events)
+		}
+	}
+	return cl.events
+}
+
+// cross вызывает все метки, смещение которых попало в интервал [from, to).
+// Вызывается из пути чтения, поэтому колбэки запускаются в отдельных горутинах.
+func (cl *cueList) cross(from, to int64) {
+	if from >= to {
+		return
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.closed {
+		return
+	}
+
+	i := sort.Search(len(cl.points), func(i int) bool { return cl.points[i].offset >= from })
+	for ; i < len(cl.points) && cl.points[i].offset < to; i++ {
+		cp := cl.points[i]
+		ev := CueEvent{ID: cp.id, Name: cp.name, Position: cp.seconds}
+		if cp.callback != nil {
+			go cp.callback(ev)
+		}
+		if cl.events != nil {
+			select {
+			case cl.events <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// close закрывает канал событий после окончания звука.
+func (cl *cueList) close() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.closed {
+		return
+	}
+	cl.closed = true
+	if cl.events != nil {
+		close(cl.events)
+	}
+}
+
+// AddCue регистрирует метку на позиции seconds играющего звука.
+// Колбэк (может быть nil) вызывается в отдельной горутине, когда воспроизведение
+// доходит до метки; то же событие отправляется в канал CueEvents.
+// Возвращает идентификатор метки для RemoveCue.
+func AddCue(done chan struct{}, seconds float64, name string, callback func(CueEvent)) (int, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return 0, fmt.Errorf("sound not found")
+	}
+
+	if seconds < 0 {
+		return 0, fmt.Errorf("cue position must not be negative")
+	}
+
+	offset := (secondsToBytes(seconds, control.sampleRate) / 4) * 4
+	return control.tracker.cues.add(offset, seconds, name, callback), nil
+}
+
+// RemoveCue удаляет ранее добавленную метку.
+func RemoveCue(done chan struct{}, id int) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if !control.tracker.cues.remove(id) {
+		return fmt.Errorf("cue %d not found", id)
+	}
+	return nil
+}
+
+// CueEvents возвращает канал, в который приходят срабатывания всех меток звука.
+// Канал закрывается, когда звук завершается.
+func CueEvents(done chan struct{}) (<-chan CueEvent, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return nil, fmt.Errorf("sound not found")
+	}
+
+	return control.tracker.cues.subscribe(), nil
+}
@@ -10,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/hajimehoshi/go-mp3"
 	"github.com/youpy/go-wav"
 )
 
@@ -80,25 +79,33 @@ func (t *tempFileCloser) Close() error {
 }
 
 // getDecoder выбирает подходящий декодер (MP3 или WAV) на основе содержимого потока.
+// Сначала формат определяется по сигнатуре, чтобы PCM-данные WAV не приняли
+// за кадры MP3, а тег ID3v2 не помешал узнать MP3.
 func getDecoder(rs io.ReadSeeker, path string) (decodedStream, error) {
+	switch format := sniffFormat(rs); format {
+	case "mp3":
+		return newMP3Stream(rs)
+	case "wav":
+		if stream, err := newWAVDecoder(rs); err == nil {
+			return stream, nil
+		}
+		return nil, fmt.Errorf("invalid wav file")
+	case "flac", "ogg":
+		return nil, fmt.Errorf("%s playback is not supported", format)
+	}
+
+	// Формат не распознан по сигнатуре — пробуем декодеры по очереди.
 	// 1. Пробуем декодировать как MP3.
-	mp3Stream, err := mp3.NewDecoder(rs)
-	if err == nil {
-		return mp3Stream, nil
+	if stream, err := newMP3Stream(rs); err == nil {
+		return stream, nil
 	}
 
 	// Сбрасываем указатель после неудачной попытки.
 	rs.Seek(0, io.SeekStart)
 
 	// 2. Пробуем декодировать как WAV.
-	// Проверяем, поддерживает ли поток метод ReadAt (необходим для WAV).
-	if rsa, ok := rs.(readSeekerAt); ok {
-		d := wav.NewReader(rsa) // Теперь здесь не будет ошибки компиляции
-		finfo, err := d.Format()
-		if err == nil {
-			rsa.Seek(0, io.SeekStart)
-			return &wavWrapper{rsa, int(finfo.SampleRate)}, nil
-		}
+	if stream, err := newWAVDecoder(rs); err == nil {
+		return stream, nil
 	}
 
 	// 3. Если ничего не помогло, смотрим на расширение для вывода ошибки
@@ -106,6 +113,22 @@ func getDecoder(rs io.ReadSeeker, path string) (decodedStream, error) {
 	return nil, fmt.Errorf("file content doesn't match extension or format is unsupported: %s", ext)
 }
 
+// newWAVDecoder создаёт декодер WAV.
+// Поток должен поддерживать метод ReadAt, который нужен библиотеке go-wav.
+func newWAVDecoder(rs io.ReadSeeker) (decodedStream, error) {
+	rsa, ok := rs.(readSeekerAt)
+	if !ok {
+		return nil, fmt.Errorf("wav stream must implement io.ReaderAt")
+	}
+	d := wav.NewReader(rsa)
+	finfo, err := d.Format()
+	if err != nil {
+		return nil, err
+	}
+	rsa.Seek(0, io.SeekStart)
+	return &wavWrapper{rsa, int(finfo.SampleRate)}, nil
+}
+
 
 // Удаляем временные файлы, ранее созданные нашей программой
 func CleanUpTempFiles() {
@@ -0,0 +1,108 @@
+package playsound
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Processor — звено цепочки обработки между декодером и плеером.
+// Process получает стерео-кадры float32 (L и R чередуются, диапазон [-1, 1])
+// и изменяет их на месте. Процессор хранит состояние фильтров,
+// поэтому один экземпляр следует подключать только к одному звуку.
+type Processor interface {
+	Process(frames []float32, sampleRate int)
+}
+
+// Tailer реализуют процессоры, которым после окончания исходного звука нужно
+// время, чтобы доиграть «хвост» (реверберация, эхо). Канал done закроется
+// только после того, как хвост прозвучит.
+type Tailer interface {
+	Tail() time.Duration
+}
+
+// effectChain — упорядоченный список процессоров одного звука.
+type effectChain struct {
+	mu         sync.Mutex
+	processors []Processor
+}
+
+// len возвращает количество подключённых процессоров.
+func (ec *effectChain) len() int {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	return len(ec.processors)
+}
+
+// add добавляет процессор в конец цепочки.
+func (ec *effectChain) add(p Processor) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.processors = append(ec.processors, p)
+}
+
+// remove убирает процессор из цепочки.
+func (ec *effectChain) remove(p Processor) bool {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	for i, cur := range ec.processors {
+		if cur == p {
+			ec.processors = append(ec.processors[:i], ec.processors[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// tail возвращает самый длинный хвост среди процессоров цепочки.
+func (ec *effectChain) tail() time.Duration {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	var longest time.Duration
+	for _, p := range ec.processors {
+		if t, ok := p.(Tailer); ok {
+			longest = max(longest, t.Tail())
+		}
+	}
+	return longest
+}
+
+// process последовательно применяет все процессоры к буферу.
+func (ec *effectChain) process(frames []float32, sampleRate int) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	for _, p := range ec.processors {
+		p.Process(frames, sampleRate)
+	}
+}
+
+// AddEffect подключает процессор к концу цепочки обработки играющего звука.
+func AddEffect(done chan struct{}, p Processor) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if p == nil {
+		return fmt.Errorf("processor is nil")
+	}
+
+	control.tracker.effects.add(p)
+	return nil
+}
+
+// RemoveEffect отключает ранее добавленный процессор.
+func RemoveEffect(done chan struct{}, p Processor) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if !control.tracker.effects.remove(p) {
+		return fmt.Errorf("effect not found")
+	}
+	return nil
+}
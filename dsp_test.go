@@ -0,0 +1,263 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// sineFrames генерирует стерео-синус в формате float32.
+func sineFrames(freq float64, rate int, frames int) []float32 {
+	buf := make([]float32, frames*2)
+	for i := 0; i < frames; i++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+		buf[i*2], buf[i*2+1] = v, v
+	}
+	return buf
+}
+
+// peak возвращает максимальную амплитуду во второй половине буфера,
+// чтобы переходный процесс фильтра не влиял на результат.
+func peak(frames []float32) float64 {
+	var p float64
+	for _, v := range frames[len(frames)/2:] {
+		p = math.Max(p, math.Abs(float64(v)))
+	}
+	return p
+}
+
+func TestFilters(t *testing.T) {
+	const rate = 44100
+
+	tests := []struct {
+		name     string
+		proc     Processor
+		freq     float64
+		wantGain float64 // Ожидаемое отношение амплитуд выход/вход
+	}{
+		{"Low-pass keeps bass", NewLowPass(1000), 100, 1},
+		{"Low-pass cuts treble", NewLowPass(1000), 8000, 0.02},
+		{"High-pass cuts bass", NewHighPass(1000), 100, 0.01},
+		{"Band-pass keeps center", NewBandPass(1000, 1), 1000, 1},
+		{"EQ boosts band", NewEqualizer(EQBand{Type: Peaking, Frequency: 1000, Q: 1, Gain: 6}), 1000, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := sineFrames(tt.freq, rate, rate/2)
+			tt.proc.Process(buf, rate)
+
+			gain := peak(buf) / 0.5
+			if math.Abs(gain-tt.wantGain) > 0.05*tt.wantGain+0.01 {
+				t.Errorf("gain = %.3f; want %.3f", gain, tt.wantGain)
+			}
+		})
+	}
+}
+
+func TestFilterRuntimeChange(t *testing.T) {
+	const rate = 44100
+	f := NewLowPass(10000)
+
+	buf := sineFrames(5000, rate, rate/4)
+	f.Process(buf, rate)
+	if peak(buf) < 0.4 {
+		t.Fatalf("5 kHz should pass a 10 kHz low-pass, peak %.3f", peak(buf))
+	}
+
+	f.SetFrequency(500)
+	buf = sineFrames(5000, rate, rate/4)
+	f.Process(buf, rate)
+	if peak(buf) > 0.01 {
+		t.Errorf("5 kHz should be cut after lowering cutoff to 500 Hz, peak %.3f", peak(buf))
+	}
+}
+
+func TestDelayEcho(t *testing.T) {
+	const rate = 1000
+	d := NewDelay(DelayParams{Time: 100 * time.Millisecond, Feedback: 0.5, Mix: 0.5})
+
+	// Одиночный импульс должен повториться через 100 мс с уменьшенной амплитудой
+	buf := make([]float32, rate*2)
+	buf[0], buf[1] = 1, 1
+	d.Process(buf, rate)
+
+	if buf[0] != 0.5 {
+		t.Errorf("dry impulse = %v; want 0.5", buf[0])
+	}
+	if buf[200] != 0.5 {
+		t.Errorf("first echo = %v; want 0.5", buf[200])
+	}
+	if buf[400] != 0.25 {
+		t.Errorf("second echo = %v; want 0.25", buf[400])
+	}
+}
+
+// Эффекты работают на частоте микшера: у файла 48 кГц на шине 44,1 кГц
+// эхо через 100 мс приходит через 4410 кадров вывода, а не через 4800
+func TestEffectsAtMixerRate(t *testing.T) {
+	const fileRate, mixRate = 48000, 44100
+	pcm := make([]byte, fileRate/2*frameBytes)
+	binary.LittleEndian.PutUint16(pcm[100*frameBytes:], 16384)
+	binary.LittleEndian.PutUint16(pcm[100*frameBytes+2:], 16384)
+
+	delay := NewDelay(DelayParams{Time: 100 * time.Millisecond, Mix: 0.5})
+	params := validateParams(PlayParams{Effects: []Processor{delay}})
+	tracker, _, err := newSoundVoice(&pcmStream{bytes.NewReader(pcm), fileRate}, params, 1, mixRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]float32, mixRate*2)
+	n, _ := tracker.readProcessed(out)
+	out = out[:n*2]
+
+	// Индексы самого громкого кадра до и после середины задержки
+	loudest := func(from, to int) int {
+		best := from
+		for i := from; i < to && i < n; i++ {
+			if math.Abs(float64(out[i*2])) > math.Abs(float64(out[best*2])) {
+				best = i
+			}
+		}
+		return best
+	}
+	dry := loudest(0, 2000)
+	echo := loudest(2000, n)
+	if got := echo - dry; got < 4400 || got > 4420 {
+		t.Errorf("echo after %d frames; want about 4410", got)
+	}
+}
+
+// Хвост эффекта должен доиграть после конца трека, прежде чем поток вернёт EOF
+func TestEffectTail(t *testing.T) {
+	const rate = 8000
+	pcm := sinePCM(440, rate, 0.5)
+
+	ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), rate}, playTails: true, tailLeft: -1}
+	rv := NewReverb(ReverbParams{RoomSize: 0.8, Damping: 0.5, Wet: 0.5})
+	ts.effects.add(rv)
+
+	out := renderStream(t, ts)
+	seconds := float64(len(out)) / rate
+	want := 0.5 + rv.Tail().Seconds()
+	if math.Abs(seconds-want) > 0.01 {
+		t.Fatalf("duration with tail = %.3f s; want %.3f s", seconds, want)
+	}
+
+	// Сразу после конца трека реверберация ещё слышна
+	var energy float64
+	for _, v := range out[rate/2 : rate/2+rate/10] {
+		energy += float64(v * v)
+	}
+	if energy == 0 {
+		t.Error("reverb tail should continue after the source ends")
+	}
+}
+
+// Два громких звука на шине не должны выходить за порог лимитера
+func TestMasterLimiterPreventsClipping(t *testing.T) {
+	const rate = 8000
+	m := newMixer()
+	m.sampleRate = rate
+
+	// Два синуса с амплитудой 0.5 в фазе дают в сумме полную шкалу
+	for i := 0; i < 2; i++ {
+		v := newVoice(&trackingStream{decodedStream: &pcmStream{bytes.NewReader(sinePCM(440, rate, 0.5)), rate}})
+		v.SetVolume(1)
+		v.Play()
+		m.add(v)
+	}
+
+	out := make([]byte, rate/2*frameBytes)
+	m.Read(out)
+	frames := make([]float32, len(out)/2)
+	bytesToFloats(out, frames)
+
+	ceiling := dbToLinear(defaultLimiterCeiling)
+	var maxPeak float64
+	for _, v := range frames {
+		maxPeak = math.Max(maxPeak, math.Abs(float64(v)))
+	}
+	if maxPeak > ceiling+1e-4 {
+		t.Errorf("mixed peak = %.4f; want <= %.4f", maxPeak, ceiling)
+	}
+	if maxPeak < 0.9 {
+		t.Errorf("limiter should not attenuate more than needed, peak %.4f", maxPeak)
+	}
+}
+
+func TestCompressorReducesLoudParts(t *testing.T) {
+	const rate = 44100
+	c := NewCompressor(CompressorParams{Threshold: -20, Ratio: 4, Attack: time.Millisecond, Release: 100 * time.Millisecond})
+
+	// Синус с пиком 0.5 (-6 дБFS) на 14 дБ выше порога: после сжатия 4:1 должно остаться 3.5 дБ
+	buf := sineFrames(1000, rate, rate/2)
+	c.Process(buf, rate)
+
+	got := linearToDB(peak(buf))
+	want := -20 + 14.0/4
+	if math.Abs(got-want) > 1 {
+		t.Errorf("compressed peak = %.2f dBFS; want about %.2f dBFS", got, want)
+	}
+}
+
+// Синус 1 кГц с пиком -6 дБFS в обоих каналах по BS.1770 даёт около -6 LUFS
+func TestIntegratedLoudness(t *testing.T) {
+	const rate = 48000
+	stream := &pcmStream{bytes.NewReader(sinePCM(1000, rate, 3)), rate}
+
+	lufs, err := integratedLoudness(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := linearToDB(0.5); math.Abs(lufs-want) > 0.2 {
+		t.Errorf("integratedLoudness() = %.2f LUFS; want %.2f", lufs, want)
+	}
+
+	// Без тега и кеша усиление неизвестно; измерение попадает в кеш,
+	// и нормализация к -18 LUFS ослабляет такой трек примерно на 12 дБ
+	loudnessCache.Lock()
+	delete(loudnessCache.values, "test-sine")
+	loudnessCache.Unlock()
+	if gain, measured := normalizationGain("test-sine", -18, 0, false); measured || gain != 1 {
+		t.Errorf("normalizationGain() before measurement = %v, %v; want 1, false", gain, measured)
+	}
+	stream.Seek(0, 0)
+	if _, err := measureStream("test-sine", stream, -18); err != nil {
+		t.Fatal(err)
+	}
+	gain, measured := normalizationGain("test-sine", -18, 0, false)
+	if got := linearToDB(gain); !measured || math.Abs(got-(-18-lufs)) > 0.01 {
+		t.Errorf("normalization gain = %.2f dB, %v; want %.2f dB", got, measured, -18-lufs)
+	}
+}
+
+// Громкость, которой нет в кеше, измеряется отдельно от запуска, а усиление
+// нормализации выводится плавно, без скачка
+func TestNormalizeLater(t *testing.T) {
+	path := writeTestWAV(t, sinePCM(1000, 48000, 1), 48000)
+	if _, measured := normalizationGain(path, -18, 0, false); measured {
+		t.Fatal("loudness is cached before measurement")
+	}
+
+	v := constantVoice(1000)
+	normalizeLater(path, -18, v, 1000)
+	want, measured := normalizationGain(path, -18, 0, false)
+	if !measured {
+		t.Fatal("normalizeLater() did not cache loudness")
+	}
+
+	ramp := int(normalizeRamp.Seconds() * 1000)
+	buf := make([]float32, ramp*2)
+	v.mixInto(buf, 0)
+	for i := 1; i < ramp; i++ {
+		if buf[i*2] >= buf[(i-1)*2] {
+			t.Fatalf("frame %d: %v after %v; want gain ramping down", i, buf[i*2], buf[(i-1)*2])
+		}
+	}
+	if got := voiceField(v, func(v *voice) float64 { return v.gain }); math.Abs(got-want) > 1e-9 {
+		t.Errorf("gain after ramp = %v; want %v", got, want)
+	}
+}
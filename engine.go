@@ -2,30 +2,74 @@ package playsound
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ebitengine/oto/v3"
 )
 
 // soundController представляет собой активную сессию проигрывания звука.
 // Она хранит всё необходимое для динамического управления потоком.
+// В activeSounds лежит указатель, поэтому все функции управления видят одно
+// и то же состояние; изменяемые поля защищены mu.
 type soundController struct {
 	cancel     context.CancelFunc // Функция для немедленной остановки горутины мониторинга и очистки ресурсов.
-	player     *oto.Player        // ЭПрямой доступ к аудио-плееру Oto для изменения громкости и паузы.
+	player     *voice             // Голос на шине микшера для изменения громкости и паузы.
 	params     PlayParams         // Настройки, переданные при старте (нужны для Loop и Fade эффектов).
 	sampleRate int                // Частота дискретизации, используется для конвертации байтов в секунды.
-	isPaused   bool               // Флаг состояния паузы. Если true, мониторинг игнорирует отсутствие воспроизведения.
 	totalBytes int64              // Общий размер аудиоданных в байтах (для расчета длительности)
 	tracker    *trackingStream    // Счётчик прогресса чтения, оборачивающий основной поток
+	tags       Tags               // Теги трека, прочитанные при запуске
+	cover      *Picture           // Встроенная обложка (nil, если её нет)
+	source     string             // Путь к файлу или URL, с которого запущен звук
+	seq        uint64             // Порядковый номер запуска: чем меньше, тем раньше запущен звук
+	started    time.Time          // Время запуска
+
+	mu       sync.Mutex
+	state    SoundState    // Текущее состояние (см. SoundState)
+	fadeTo   SoundState    // Состояние, в которое звук перейдёт после StateFading
+	fadeGen  uint64        // Поколение перехода: растёт при каждом переходе и прерывает устаревшую плавность
+	volume   float64       // Громкость, заданная пользователем: к ней звук возвращается после паузы и нарастания
+	stopFade time.Duration // Длительность затухания при остановке (см. stop)
 }
 
-// updateStatus безопасно обновляет флаг паузы в карте активных звуков.
-func (sc *soundController) updateStatus(done chan struct{}, paused bool) {
-	activeMu.Lock()
-	defer activeMu.Unlock()
-	sc.isPaused = paused
-	activeSounds[done] = *sc
+// soundSeq выдаёт порядковые номера запускаемым звукам.
+var soundSeq atomic.Uint64
+
+// audiblePos возвращает позицию в байтах, которая сейчас звучит из динамиков:
+// прочитанные данные минус буфер микшера и задержка устройства вывода.
+// На паузе это последний смешанный кадр: устройство уже доиграло буфер.
+func (sc *soundController) audiblePos() int64 {
+	// Буферы после обработки хранят данные в темпе вывода, поэтому пересчитываем их
+	// в байты исходного трека с учётом скорости.
+	var pending time.Duration
+	if sc.State() != StatePaused {
+		pending = OutputLatency()
+		if sc.player != nil {
+			// После снятия с паузы в буфере ещё не весь звук этого голоса,
+			// поэтому вычитаем не больше, чем голос успел смешать
+			pending = min(pending+sc.player.bufferedDuration(), sc.player.sinceResume())
+		}
+	}
+	delta := secondsToBytes(pending.Seconds()*sc.tracker.Speed(), sc.sampleRate)
+	pos := sc.tracker.CurrentPos()
+	if sc.tracker.Reversed() {
+		// При обратном воспроизведении слышимая позиция отстаёт «сверху»
+		pos += delta
+		if sc.totalBytes > 0 && pos > sc.totalBytes {
+			pos = sc.totalBytes
+		}
+	} else {
+		pos -= delta
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	return (pos / 4) * 4
 }
 
 // decodedStream объединяет возможности чтения и получения частоты дискретизации.
@@ -58,11 +102,11 @@ func (w *wavWrapper) Length() int64 {
 }
 
 // initEngine инициализирует аудио-движок Oto один раз за все время работы программы.
+// Все звуки смешиваются микшером и выводятся через один плеер oto с частотой sampleRate.
 func initEngine(sampleRate int) error {
 	var err error
 	once.Do(func() {
 		CleanUpTempFiles()
-		rootCtx, rootCancel = context.WithCancel(context.Background())
 		op := &oto.NewContextOptions{
 			SampleRate:   sampleRate,
 			ChannelCount: 2,
@@ -72,6 +116,7 @@ func initEngine(sampleRate int) error {
 		otoCtx, readyChan, err = oto.NewContext(op)
 		if err == nil {
 			<-readyChan
+			masterMixer.attach(otoCtx, sampleRate)
 		}
 	})
 	return err
@@ -81,15 +126,38 @@ func initEngine(sampleRate int) error {
 var (
 	otoCtx       *oto.Context
 	once         sync.Once
-	mu           sync.Mutex
-	rootCtx      context.Context
-	rootCancel   context.CancelFunc
-	activeSounds = make(map[chan struct{}]soundController)
+	activeSounds = make(map[chan struct{}]*soundController)
 	activeMu     sync.Mutex
+	stopEpoch    uint64 // Число вызовов StopAll; защищено activeMu
+	masterMixer  = newMixer()
 )
 
+// defaultOutputLatency — оценка задержки аудиоустройства (размер буфера драйвера).
+const defaultOutputLatency = 50 * time.Millisecond
+
+// outputLatency хранит задержку устройства вывода в наносекундах.
+var outputLatency atomic.Int64
+
+func init() {
+	outputLatency.Store(int64(defaultOutputLatency))
+}
+
+// SetOutputLatency задаёт задержку устройства вывода, которая учитывается в GetPosition.
+// Значение по умолчанию — 50 мс; его стоит откалибровать под конкретное устройство.
+func SetOutputLatency(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	outputLatency.Store(int64(d))
+}
+
+// OutputLatency возвращает текущую оценку задержки устройства вывода.
+func OutputLatency() time.Duration {
+	return time.Duration(outputLatency.Load())
+}
+
 // getControl — хелпер для безопасного получения контроллера из карты.
-func getControl(done chan struct{}) (soundController, bool) {
+func getControl(done chan struct{}) (*soundController, bool) {
 	activeMu.Lock()
 	defer activeMu.Unlock()
 	ctrl, ok := activeSounds[done]
@@ -103,6 +171,16 @@ type trackingStream struct {
 	decodedStream
 	currentPos int64
 	mu         sync.Mutex
+	cues       cueList     // Метки на треке, срабатывающие при пересечении позиции чтения
+	rate       rateStage   // Изменение скорости и высоты тона
+	reverse    bool        // Чтение кадров в обратном порядке
+	effects    effectChain // Цепочка DSP-процессоров после изменения скорости
+	space      panner      // Панорама и положение в 3D-пространстве
+	meter      meter       // Измеритель уровней и спектра выходного сигнала
+	playTails  bool        // Доигрывать хвосты эффектов после конца трека (выключено при Loop)
+	tailLeft   int64       // Сколько кадров хвоста осталось; -1 — хвост ещё не начался
+	raw        []byte      // Буфер для чтения декодера при обработке в float
+	frames     []float32   // Буфер обработанных кадров
 }
 
 // Read считывает данные из декодера и обновляет счетчик прочитанных байт.
@@ -111,25 +189,266 @@ func (ts *trackingStream) Read(p []byte) (n int, err error) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 	
-	n, err = ts.decodedStream.Read(p)
-	ts.currentPos += int64(n)
+	frames := len(p) / frameBytes
+	if cap(ts.frames) < frames*2 {
+		ts.frames = make([]float32, frames*2)
+	}
+	buf := ts.frames[:frames*2]
+
+	if !ts.needsFloat() {
+		n, err = ts.decodedStream.Read(p)
+		ts.advance(int64(n))
+		got := n / frameBytes
+		bytesToFloats(p[:got*frameBytes], buf[:got*2])
+		ts.meter.write(buf[:got*2], ts.outputRate())
+		return n, err
+	}
+
+	// При обработке данные проходят через float-кадры.
+	got, err := ts.process(buf)
+	floatsToBytes(buf[:got*2], p)
+	ts.meter.write(buf[:got*2], ts.outputRate())
+	return got * frameBytes, err
+}
+
+// readProcessed заполняет dst обработанными float-кадрами. Так голос микшера
+// получает данные без лишнего перевода в int16 и обратно.
+func (ts *trackingStream) readProcessed(dst []float32) (int, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var n int
+	var err error
+	if !ts.needsFloat() {
+		n, err = ts.readFrames(dst)
+	} else {
+		n, err = ts.process(dst)
+	}
+	ts.meter.write(dst[:n*2], ts.outputRate())
 	return n, err
 }
 
+// OutputRate — потокобезопасная версия outputRate.
+func (ts *trackingStream) OutputRate() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.outputRate()
+}
+
+// outputRate возвращает частоту дискретизации обработанных кадров:
+// после ресемплинга она совпадает с частотой микшера.
+func (ts *trackingStream) outputRate() int {
+	if ts.rate.srcRatio > 0 {
+		return int(math.Round(float64(ts.SampleRate()) / ts.rate.srcRatio))
+	}
+	return ts.SampleRate()
+}
+
+// process пропускает исходные кадры через изменение скорости, хвосты и цепочку эффектов.
+// Эффекты получают кадры уже на частоте микшера. Положение 3D-источника
+// пересчитывается на каждый буфер.
+func (ts *trackingStream) process(buf []float32) (int, error) {
+	var left, right float64
+	if ts.space.active() {
+		left, right, ts.rate.doppler = ts.space.targets()
+	}
+
+	got, err := ts.rate.read(buf, ts.readFrames)
+	if err == io.EOF && ts.playTails {
+		got, err = ts.fillTail(buf, got)
+	}
+	ts.effects.process(buf[:got*2], ts.outputRate())
+	if ts.space.active() {
+		ts.space.process(buf[:got*2], left, right)
+	}
+	return got, err
+}
+
+// fillTail дополняет буфер тишиной после конца трека, чтобы эффекты доиграли хвост.
+// Возвращает io.EOF, только когда хвост закончился.
+func (ts *trackingStream) fillTail(buf []float32, got int) (int, error) {
+	if ts.tailLeft < 0 {
+		ts.tailLeft = secondsToBytes(ts.effects.tail().Seconds(), ts.outputRate()) / frameBytes
+	}
+
+	n := min(int64(len(buf)/2-got), ts.tailLeft)
+	clear(buf[got*2 : (got+int(n))*2])
+	ts.tailLeft -= n
+	got += int(n)
+
+	if ts.tailLeft == 0 {
+		return got, io.EOF
+	}
+	return got, nil
+}
+
+// needsFloat сообщает, нужна ли обработка в float, или байты декодера можно отдавать как есть.
+func (ts *trackingStream) needsFloat() bool {
+	return ts.rate.active() || ts.reverse || ts.effects.len() > 0 || ts.space.active()
+}
+
+// readFrames читает из декодера целые кадры и переводит их в float.
+// Позиция и метки считаются по исходному потоку, независимо от скорости.
+func (ts *trackingStream) readFrames(dst []float32) (int, error) {
+	if ts.reverse {
+		return ts.readFramesReverse(dst)
+	}
+
+	need := len(dst) / 2 * frameBytes
+	if cap(ts.raw) < need {
+		ts.raw = make([]byte, need)
+	}
+	raw := ts.raw[:need]
+
+	n, err := io.ReadFull(ts.decodedStream, raw)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	frames := n / frameBytes
+	ts.advance(int64(frames * frameBytes))
+	bytesToFloats(raw[:frames*frameBytes], dst[:frames*2])
+	return frames, err
+}
+
+// readFramesReverse читает блок кадров перед текущей позицией и выдаёт их в обратном порядке.
+// Декодер перематывается к началу блока, а позиция уменьшается до него же.
+func (ts *trackingStream) readFramesReverse(dst []float32) (int, error) {
+	frames := min(int64(len(dst)/2), ts.currentPos/frameBytes)
+	if frames <= 0 {
+		return 0, io.EOF
+	}
+	start := ts.currentPos - frames*frameBytes
+
+	if _, err := ts.decodedStream.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	need := int(frames * frameBytes)
+	if cap(ts.raw) < need {
+		ts.raw = make([]byte, need)
+	}
+	raw := ts.raw[:need]
+	n, err := io.ReadFull(ts.decodedStream, raw)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+
+	got := n / frameBytes
+	bytesToFloats(raw[:got*frameBytes], dst[:got*2])
+	for i, j := 0, got-1; i < j; i, j = i+1, j-1 {
+		dst[i*2], dst[j*2] = dst[j*2], dst[i*2]
+		dst[i*2+1], dst[j*2+1] = dst[j*2+1], dst[i*2+1]
+	}
+
+	ts.cues.cross(start, ts.currentPos)
+	ts.currentPos = start
+	return got, nil
+}
+
+// advance сдвигает позицию на n прочитанных байт и вызывает пройденные метки.
+func (ts *trackingStream) advance(n int64) {
+	ts.cues.cross(ts.currentPos, ts.currentPos+n)
+	ts.currentPos += n
+}
+
+// Speed возвращает итоговую скорость воспроизведения относительно исходного трека.
+func (ts *trackingStream) Speed() float64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.rate.doppler > 0 {
+		return ts.rate.playbackSpeed() * ts.rate.doppler
+	}
+	return ts.rate.playbackSpeed()
+}
+
 // Seek изменяет позицию в декодере и синхронизирует внутренний счетчик.
 // Используется для ручной перемотки трека пользователем.
 func (ts *trackingStream) Seek(offset int64, whence int) (int64, error) {
 	ts.mu.Lock()
     defer ts.mu.Unlock()
 
+	if ts.reverse {
+		return ts.seekReverse(offset, whence)
+	}
+
 	safeOffset := (offset / 4) * 4
 	newPos, err := ts.decodedStream.Seek(safeOffset, whence)
 	if err == nil {
 		ts.currentPos = newPos
+		ts.rate.reset()
+		ts.tailLeft = -1
 	}
 	return newPos, err
 }
 
+// seekReverse только запоминает новую позицию: при обратном чтении декодер
+// перематывается перед каждым блоком, а перемотка к самому концу MP3 вернула бы ошибку.
+func (ts *trackingStream) seekReverse(offset int64, whence int) (int64, error) {
+	length := streamLength(ts.decodedStream)
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = ts.currentPos + offset
+	case io.SeekEnd:
+		if length <= 0 {
+			return 0, fmt.Errorf("stream length is unknown")
+		}
+		pos = length + offset
+	default:
+		return 0, fmt.Errorf("invalid whence")
+	}
+
+	if length > 0 && pos > length {
+		pos = length
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	ts.currentPos = (pos / 4) * 4
+	ts.rate.reset()
+	ts.tailLeft = -1
+	return ts.currentPos, nil
+}
+
+// setReverse меняет направление чтения. При возврате к прямому направлению
+// декодер перематывается к текущей позиции.
+func (ts *trackingStream) setReverse(reverse bool) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.reverse == reverse {
+		return nil
+	}
+	if !reverse {
+		if _, err := ts.decodedStream.Seek(ts.currentPos, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	ts.reverse = reverse
+	ts.rate.reset()
+	return nil
+}
+
+// Reversed сообщает, читается ли поток в обратном направлении.
+func (ts *trackingStream) Reversed() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.reverse
+}
+
+// Rewind возвращает поток к началу очередного круга Loop:
+// к началу трека или к его концу при обратном воспроизведении.
+func (ts *trackingStream) Rewind() error {
+	if ts.Reversed() {
+		_, err := ts.Seek(0, io.SeekEnd)
+		return err
+	}
+	_, err := ts.Seek(0, io.SeekStart)
+	return err
+}
+
 // CurrentPos возвращает точное количество байт, прошедших через поток.
 // Используется функцией GetPosition для отображения времени в UI.
 func (ts *trackingStream) CurrentPos() int64 {
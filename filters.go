@@ -0,0 +1,217 @@
+package playsound
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// FilterType определяет форму частотной характеристики фильтра.
+type FilterType int
+
+const (
+	LowPass   FilterType = iota // Пропускает частоты ниже Frequency
+	HighPass                    // Пропускает частоты выше Frequency
+	BandPass                    // Пропускает полосу вокруг Frequency шириной, заданной Q
+	Peaking                     // Колокол: усиление или ослабление полосы на Gain дБ
+	LowShelf                    // Полка: усиление или ослабление всего ниже Frequency
+	HighShelf                   // Полка: усиление или ослабление всего выше Frequency
+)
+
+// defaultQ соответствует фильтру Баттерворта второго порядка.
+const defaultQ = 1 / math.Sqrt2
+
+// biquad — коэффициенты фильтра второго порядка и состояние для двух каналов.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             [2]float64 // Транспонированная прямая форма II
+}
+
+// setCoefficients рассчитывает коэффициенты по формулам RBJ Audio EQ Cookbook.
+func (bq *biquad) setCoefficients(typ FilterType, freq, q, gainDB float64, sampleRate int) {
+	nyquist := float64(sampleRate) / 2
+	freq = math.Min(math.Max(freq, 10), nyquist*0.99)
+	if q <= 0 {
+		q = defaultQ
+	}
+
+	w0 := 2 * math.Pi * freq / float64(sampleRate)
+	cos, sin := math.Cos(w0), math.Sin(w0)
+	alpha := sin / (2 * q)
+	A := math.Pow(10, gainDB/40)
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch typ {
+	case LowPass:
+		b0, b1, b2 = (1-cos)/2, 1-cos, (1-cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case HighPass:
+		b0, b1, b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case BandPass:
+		b0, b1, b2 = alpha, 0, -alpha
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case Peaking:
+		b0, b1, b2 = 1+alpha*A, -2*cos, 1-alpha*A
+		a0, a1, a2 = 1+alpha/A, -2*cos, 1-alpha/A
+	case LowShelf:
+		sq := 2 * math.Sqrt(A) * alpha
+		b0 = A * ((A + 1) - (A-1)*cos + sq)
+		b1 = 2 * A * ((A - 1) - (A+1)*cos)
+		b2 = A * ((A + 1) - (A-1)*cos - sq)
+		a0 = (A + 1) + (A-1)*cos + sq
+		a1 = -2 * ((A - 1) + (A+1)*cos)
+		a2 = (A + 1) + (A-1)*cos - sq
+	case HighShelf:
+		sq := 2 * math.Sqrt(A) * alpha
+		b0 = A * ((A + 1) + (A-1)*cos + sq)
+		b1 = -2 * A * ((A - 1) + (A+1)*cos)
+		b2 = A * ((A + 1) + (A-1)*cos - sq)
+		a0 = (A + 1) - (A-1)*cos + sq
+		a1 = 2 * ((A - 1) - (A+1)*cos)
+		a2 = (A + 1) - (A-1)*cos - sq
+	}
+
+	bq.b0, bq.b1, bq.b2 = b0/a0, b1/a0, b2/a0
+	bq.a1, bq.a2 = a1/a0, a2/a0
+}
+
+// process фильтрует стерео-кадры на месте.
+func (bq *biquad) process(frames []float32) {
+	for i := 0; i+1 < len(frames); i += 2 {
+		for ch := 0; ch < 2; ch++ {
+			x := float64(frames[i+ch])
+			y := bq.b0*x + bq.z1[ch]
+			bq.z1[ch] = bq.b1*x - bq.a1*y + bq.z2[ch]
+			bq.z2[ch] = bq.b2*x - bq.a2*y
+			frames[i+ch] = float32(y)
+		}
+	}
+}
+
+// Filter — фильтр второго порядка, параметры которого можно менять во время воспроизведения.
+type Filter struct {
+	mu         sync.Mutex
+	typ        FilterType
+	freq       float64
+	q          float64
+	gain       float64
+	bq         biquad
+	sampleRate int  // Частота, для которой рассчитаны коэффициенты
+	dirty      bool // Параметры изменились, коэффициенты нужно пересчитать
+}
+
+// NewFilter создаёт фильтр заданного типа. Gain (дБ) используется только
+// для Peaking, LowShelf и HighShelf; q <= 0 заменяется на 0.707.
+func NewFilter(typ FilterType, freq, q, gainDB float64) *Filter {
+	return &Filter{typ: typ, freq: freq, q: q, gain: gainDB, dirty: true}
+}
+
+// NewLowPass создаёт фильтр нижних частот с частотой среза cutoff.
+func NewLowPass(cutoff float64) *Filter {
+	return NewFilter(LowPass, cutoff, defaultQ, 0)
+}
+
+// NewHighPass создаёт фильтр верхних частот с частотой среза cutoff.
+func NewHighPass(cutoff float64) *Filter {
+	return NewFilter(HighPass, cutoff, defaultQ, 0)
+}
+
+// NewBandPass создаёт полосовой фильтр с центром center и добротностью q.
+func NewBandPass(center, q float64) *Filter {
+	return NewFilter(BandPass, center, q, 0)
+}
+
+// SetFrequency меняет частоту среза (или центральную частоту) фильтра.
+func (f *Filter) SetFrequency(hz float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.freq, f.dirty = hz, true
+}
+
+// SetQ меняет добротность фильтра.
+func (f *Filter) SetQ(q float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.q, f.dirty = q, true
+}
+
+// SetGain меняет усиление в дБ для Peaking и полочных фильтров.
+func (f *Filter) SetGain(db float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gain, f.dirty = db, true
+}
+
+// Process реализует Processor.
+func (f *Filter) Process(frames []float32, sampleRate int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dirty || f.sampleRate != sampleRate {
+		f.bq.setCoefficients(f.typ, f.freq, f.q, f.gain, sampleRate)
+		f.sampleRate, f.dirty = sampleRate, false
+	}
+	f.bq.process(frames)
+}
+
+// EQBand описывает одну полосу параметрического эквалайзера.
+type EQBand struct {
+	Type      FilterType // Обычно Peaking, LowShelf или HighShelf
+	Frequency float64    // Центральная частота или частота среза, Гц
+	Q         float64    // Добротность (ширина полосы)
+	Gain      float64    // Усиление в дБ
+}
+
+// Equalizer — многополосный параметрический эквалайзер из последовательных фильтров.
+type Equalizer struct {
+	mu    sync.Mutex
+	bands []EQBand
+	chain []*Filter
+}
+
+// NewEqualizer создаёт эквалайзер с заданными полосами.
+func NewEqualizer(bands ...EQBand) *Equalizer {
+	eq := &Equalizer{}
+	for _, b := range bands {
+		eq.bands = append(eq.bands, b)
+		eq.chain = append(eq.chain, NewFilter(b.Type, b.Frequency, b.Q, b.Gain))
+	}
+	return eq
+}
+
+// SetBand меняет параметры полосы с индексом i во время воспроизведения.
+func (eq *Equalizer) SetBand(i int, band EQBand) error {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	if i < 0 || i >= len(eq.bands) {
+		return fmt.Errorf("eq band %d out of range", i)
+	}
+
+	f := eq.chain[i]
+	f.mu.Lock()
+	// Тип полосы меняется вместе с параметрами, состояние фильтра сохраняется
+	f.typ, f.freq, f.q, f.gain, f.dirty = band.Type, band.Frequency, band.Q, band.Gain, true
+	f.mu.Unlock()
+
+	eq.bands[i] = band
+	return nil
+}
+
+// Bands возвращает копию текущих настроек полос.
+func (eq *Equalizer) Bands() []EQBand {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	return append([]EQBand(nil), eq.bands...)
+}
+
+// Process реализует Processor.
+func (eq *Equalizer) Process(frames []float32, sampleRate int) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	for _, f := range eq.chain {
+		f.Process(frames, sampleRate)
+	}
+}
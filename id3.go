@@ -0,0 +1,340 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3Frame — один кадр тега ID3v2 (идентификатор и необработанные данные).
+type id3Frame struct {
+	id   string
+	data []byte
+}
+
+// id3Tag — разобранный тег ID3v2.
+type id3Tag struct {
+	version byte  // Мажорная версия: 2, 3 или 4
+	size    int64 // Полный размер тега в байтах вместе с заголовком
+	frames  []id3Frame
+}
+
+// syncsafe декодирует 28-битное число, в котором старший бит каждого байта равен нулю.
+func syncsafe(b []byte) int64 {
+	var n int64
+	for _, c := range b {
+		n = n<<7 | int64(c&0x7f)
+	}
+	return n
+}
+
+// removeUnsync убирает байты 0x00, вставленные после 0xFF схемой unsynchronisation.
+func removeUnsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xff && i+1 < len(b) && b[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+// readID3v2 читает тег ID3v2 из начала r. Если тега нет, возвращает nil без ошибки.
+func readID3v2(r io.Reader) (*id3Tag, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil
+	}
+	if string(header[:3]) != "ID3" {
+		return nil, nil
+	}
+
+	tag := &id3Tag{version: header[3]}
+	flags := header[5]
+	size := syncsafe(header[6:10])
+	tag.size = size + 10
+	if flags&0x10 != 0 {
+		tag.size += 10 // Футер в ID3v2.4
+	}
+
+	// Размер берётся из файла, поэтому тег читается по кадрам: обрезанный
+	// или испорченный файл не заставит выделить память под весь тег,
+	// а кадр больше maxTagChunk (обычно огромная обложка) пропускается,
+	// не лишая файл названия и исполнителя.
+	body := &io.LimitedReader{R: r, N: size}
+	var br io.Reader = body
+	if flags&0x80 != 0 && tag.version < 4 {
+		br = &unsyncReader{r: body}
+	}
+	// Пропущенные кадры по возможности перематываются, а не читаются:
+	// тег разбирают ради одного размера при каждом определении формата
+	skip := func(n int64) error {
+		if seeker, ok := r.(io.Seeker); ok && br == io.Reader(body) {
+			if _, err := seeker.Seek(n, io.SeekCurrent); err != nil {
+				return err
+			}
+			body.N -= n
+			return nil
+		}
+		_, err := io.CopyN(io.Discard, br, n)
+		return err
+	}
+	truncated := func(err error) (*id3Tag, error) {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("id3: truncated tag: %d of %d bytes", size-body.N, size)
+		}
+		return nil, fmt.Errorf("id3: %v", err)
+	}
+
+	// Расширенный заголовок пропускаем
+	if flags&0x40 != 0 {
+		ext := make([]byte, 4)
+		if _, err := io.ReadFull(br, ext); err != nil {
+			return truncated(err)
+		}
+		extSize := int64(binary.BigEndian.Uint32(ext))
+		if tag.version == 4 {
+			extSize = syncsafe(ext) - 4
+		}
+		if extSize > body.N {
+			return tag, nil
+		}
+		if err := skip(extSize); err != nil {
+			return truncated(err)
+		}
+	}
+
+	idLen, headerLen := 4, 10
+	if tag.version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	header = make([]byte, headerLen)
+	for body.N >= int64(headerLen) {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return truncated(err)
+		}
+		if header[0] == 0 {
+			break // Дополнение после кадров
+		}
+		id := string(header[:idLen])
+		var frameSize int64
+		var frameFlags uint16
+		switch tag.version {
+		case 2:
+			frameSize = int64(header[3])<<16 | int64(header[4])<<8 | int64(header[5])
+		case 4:
+			frameSize = syncsafe(header[4:8])
+			frameFlags = binary.BigEndian.Uint16(header[8:10])
+		default:
+			frameSize = int64(binary.BigEndian.Uint32(header[4:8]))
+			frameFlags = binary.BigEndian.Uint16(header[8:10])
+		}
+
+		if frameSize > body.N {
+			break
+		}
+		if frameSize > maxTagChunk {
+			if err := skip(frameSize); err != nil {
+				return truncated(err)
+			}
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(br, frameSize))
+		if err != nil {
+			return truncated(err)
+		}
+		if int64(len(data)) < frameSize {
+			return truncated(io.ErrUnexpectedEOF)
+		}
+
+		if tag.version == 4 {
+			if frameFlags&0x0001 != 0 && len(data) >= 4 {
+				data = data[4:] // Индикатор длины данных
+			}
+			if frameFlags&0x0002 != 0 {
+				data = removeUnsync(data)
+			}
+		}
+		tag.frames = append(tag.frames, id3Frame{id: id, data: data})
+	}
+	return tag, nil
+}
+
+// unsyncReader убирает на лету байты 0x00, вставленные после 0xFF
+// схемой unsynchronisation.
+type unsyncReader struct {
+	r      io.Reader
+	prevFF bool
+}
+
+func (u *unsyncReader) Read(p []byte) (int, error) {
+	for {
+		n, err := u.r.Read(p)
+		out := 0
+		for _, c := range p[:n] {
+			if u.prevFF && c == 0 {
+				u.prevFF = false
+				continue
+			}
+			u.prevFF = c == 0xff
+			p[out] = c
+			out++
+		}
+		if out > 0 || err != nil || n == 0 {
+			return out, err
+		}
+	}
+}
+
+// decodeID3Text переводит текст кадра в UTF-8 с учётом байта кодировки.
+func decodeID3Text(enc byte, b []byte) string {
+	switch enc {
+	case 1, 2: // UTF-16 с BOM или UTF-16BE
+		bigEndian := enc == 2
+		if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+			bigEndian, b = true, b[2:]
+		} else if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+			bigEndian, b = false, b[2:]
+		}
+		u := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			if bigEndian {
+				u = append(u, binary.BigEndian.Uint16(b[i:]))
+			} else {
+				u = append(u, binary.LittleEndian.Uint16(b[i:]))
+			}
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	case 3: // UTF-8
+		return strings.TrimRight(string(b), "\x00")
+	default: // ISO-8859-1
+		r := make([]rune, 0, len(b))
+		for _, c := range b {
+			r = append(r, rune(c))
+		}
+		return strings.TrimRight(string(r), "\x00")
+	}
+}
+
+// splitID3Text делит данные кадра по терминатору строки,
+// который в UTF-16 занимает два байта.
+func splitID3Text(enc byte, b []byte) (string, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return decodeID3Text(enc, b[:i]), b[i+2:]
+			}
+		}
+		return decodeID3Text(enc, b), nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return decodeID3Text(enc, b[:i]), b[i+1:]
+	}
+	return decodeID3Text(enc, b), nil
+}
+
+// userText возвращает значение кадра TXXX с описанием desc (без учёта регистра).
+func (t *id3Tag) userText(desc string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	for _, f := range t.frames {
+		if (f.id != "TXXX" && f.id != "TXX") || len(f.data) < 1 {
+			continue
+		}
+		d, rest := splitID3Text(f.data[0], f.data[1:])
+		if strings.EqualFold(d, desc) {
+			return decodeID3Text(f.data[0], rest), true
+		}
+	}
+	return "", false
+}
+
+// id3TextFields сопоставляет текстовые кадры ID3v2 (и их трёхбуквенные аналоги из v2.2)
+// с именами тегов, общими для всех форматов.
+var id3TextFields = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TPE2": "albumartist", "TP2": "albumartist",
+	"TALB": "album", "TAL": "album",
+	"TRCK": "track", "TRK": "track",
+	"TPOS": "disc", "TPA": "disc",
+	"TYER": "year", "TYE": "year", "TDRC": "year",
+	"TCON": "genre", "TCO": "genre",
+	"TCOM": "composer", "TCM": "composer",
+}
+
+// textFields возвращает текстовые теги: известные кадры под общими именами,
+// кадры TXXX — под своим описанием в нижнем регистре.
+func (t *id3Tag) textFields() map[string]string {
+	fields := make(map[string]string)
+	if t == nil {
+		return fields
+	}
+	for _, f := range t.frames {
+		if len(f.data) < 1 {
+			continue
+		}
+		enc := f.data[0]
+		if f.id == "TXXX" || f.id == "TXX" {
+			desc, rest := splitID3Text(enc, f.data[1:])
+			fields[strings.ToLower(desc)] = decodeID3Text(enc, rest)
+			continue
+		}
+		if name, ok := id3TextFields[f.id]; ok {
+			// В v2.4 значения могут разделяться нулём — оставляем первое
+			value, _ := splitID3Text(enc, f.data[1:])
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// picture возвращает встроенную обложку из кадра APIC (PIC в v2.2).
+// Если картинок несколько, предпочитается лицевая сторона обложки.
+func (t *id3Tag) picture() *Picture {
+	if t == nil {
+		return nil
+	}
+	var found *Picture
+	for _, f := range t.frames {
+		if (f.id != "APIC" && f.id != "PIC") || len(f.data) < 2 {
+			continue
+		}
+		enc, b := f.data[0], f.data[1:]
+
+		var mime string
+		if f.id == "PIC" {
+			if len(b) < 3 {
+				continue
+			}
+			// В v2.2 вместо MIME-типа трёхбуквенный формат изображения
+			mime = "image/" + strings.ToLower(string(b[:3]))
+			if mime == "image/jpg" {
+				mime = "image/jpeg"
+			}
+			b = b[3:]
+		} else {
+			i := bytes.IndexByte(b, 0)
+			if i < 0 {
+				continue
+			}
+			mime, b = string(b[:i]), b[i+1:]
+		}
+		if len(b) < 1 {
+			continue
+		}
+		pic := &Picture{MIMEType: mime, Type: b[0]}
+		pic.Description, pic.Data = splitID3Text(enc, b[1:])
+
+		if found == nil || (pic.Type == PictureFrontCover && found.Type != PictureFrontCover) {
+			found = pic
+		}
+	}
+	return found
+}
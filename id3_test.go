@@ -0,0 +1,94 @@
+package playsound
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// id3v23 собирает тег ID3v2.3 из пар «идентификатор кадра — данные».
+func id3v23(frames ...[2]string) []byte {
+	var body bytes.Buffer
+	for _, f := range frames {
+		size := len(f[1])
+		body.WriteString(f[0])
+		body.Write([]byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size), 0, 0})
+		body.WriteString(f[1])
+	}
+	size := body.Len()
+	header := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(header, body.Bytes()...)
+}
+
+func TestReadReplayGain(t *testing.T) {
+	tag := id3v23(
+		[2]string{"TIT2", "\x00Song"},
+		[2]string{"TXXX", "\x00replaygain_track_gain\x00-6.54 dB"},
+	)
+	rs := bytes.NewReader(append(tag, 0xff, 0xfb))
+
+	tags, _ := readMetadata(rs)
+	gain, ok := parseReplayGain(tags["replaygain_track_gain"])
+	if !ok || gain != -6.54 {
+		t.Errorf("replaygain_track_gain = %v, %v; want -6.54, true", gain, ok)
+	}
+	if pos, _ := rs.Seek(0, 1); pos != 0 {
+		t.Errorf("stream should be rewound, position %d", pos)
+	}
+
+	tags, _ = readMetadata(bytes.NewReader([]byte("RIFF....")))
+	if _, ok := parseReplayGain(tags["replaygain_track_gain"]); ok {
+		t.Error("file without ID3 should have no ReplayGain")
+	}
+}
+
+// zeros — бесконечный поток нулевых байт.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// Кадр больше maxTagChunk пропускается, не лишая тег остальных кадров,
+// а размер тега из заголовка не приводит к выделению памяти под весь тег
+func TestID3SizeLimit(t *testing.T) {
+	coverSize := maxTagChunk + 1
+	title := id3v23([2]string{"TIT2", "\x00Song"})[10:]
+	size := 10 + coverSize + len(title)
+	head := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	head = append(head, 'A', 'P', 'I', 'C', byte(coverSize>>24), byte(coverSize>>16), byte(coverSize>>8), byte(coverSize), 0, 0)
+
+	tag, err := readID3v2(io.MultiReader(bytes.NewReader(head), io.LimitReader(zeros{}, int64(coverSize)), bytes.NewReader(title)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.size != int64(size)+10 || len(tag.frames) != 1 || tag.frames[0].id != "TIT2" {
+		t.Errorf("readID3v2() = size %d, %d frames; want size %d with only TIT2", tag.size, len(tag.frames), size+10)
+	}
+	if got := tag.textFields()["title"]; got != "Song" {
+		t.Errorf("title = %q; want Song", got)
+	}
+
+	truncated := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0x7f, 0x7f, 'T', 'I', 'T', '2'}
+	if _, err := readID3v2(bytes.NewReader(truncated)); err == nil {
+		t.Error("expected error for truncated tag")
+	}
+	huge := []byte{'I', 'D', '3', 3, 0, 0, 0x7f, 0x7f, 0x7f, 0x7f, 'T', 'I', 'T', '2', 0, 0x10, 0, 0, 0, 0}
+	if _, err := readID3v2(bytes.NewReader(huge)); err == nil {
+		t.Error("expected error for truncated huge tag")
+	}
+}
+
+// Тег со снятой unsynchronisation: нулевой байт после 0xFF не входит в кадр
+func TestID3Unsync(t *testing.T) {
+	frame := []byte{'T', 'I', 'T', '2', 0, 0, 0, 3, 0, 0, 0, 0xff, 0, 'A'}
+	head := []byte{'I', 'D', '3', 3, 0, 0x80, 0, 0, 0, byte(len(frame))}
+	tag, err := readID3v2(bytes.NewReader(append(head, frame...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tag.textFields()["title"]; got != "ÿA" {
+		t.Errorf("title = %q; want %q", got, "ÿA")
+	}
+}
@@ -0,0 +1,316 @@
+package playsound
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VoicePolicy определяет, что делать с новым звуком, когда лимит голосов исчерпан.
+type VoicePolicy int
+
+const (
+	RejectNew           VoicePolicy = iota // Не запускать новый звук и вернуть ошибку
+	StealOldest                            // Остановить самый давно запущенный звук
+	StealQuietest                          // Остановить самый тихий звук
+	StealLowestPriority                    // Остановить звук с наименьшим PlayParams.Priority
+)
+
+// voiceLimit — ограничение числа одновременно звучащих голосов.
+type voiceLimit struct {
+	max    int // 0 — без ограничения
+	policy VoicePolicy
+}
+
+// RetriggerPolicy определяет, что делать при повторном запуске источника,
+// когда исчерпан лимит экземпляров или не прошёл интервал перезапуска.
+type RetriggerPolicy int
+
+const (
+	RetriggerStack   RetriggerPolicy = iota // Запустить ещё один экземпляр поверх; при лимите остановить самый старый
+	RetriggerSkip                           // Не запускать новый экземпляр и вернуть ошибку
+	RetriggerRestart                        // Перезапустить уже играющий экземпляр с начала и вернуть его канал
+)
+
+// SourceLimit — ограничения для одного источника (пути к файлу или URL).
+type SourceLimit struct {
+	MaxInstances int             // Сколько экземпляров источника может звучать одновременно (0 — без ограничения)
+	MinInterval  time.Duration   // Минимальный интервал между запусками (на RetriggerStack не действует)
+	Policy       RetriggerPolicy // Что делать при превышении
+}
+
+var limits = struct {
+	sync.Mutex
+	global    voiceLimit
+	groups    map[string]voiceLimit
+	sources   map[string]SourceLimit
+	lastStart map[string]time.Time // Время последнего запуска источников с лимитом
+}{
+	groups:    make(map[string]voiceLimit),
+	sources:   make(map[string]SourceLimit),
+	lastStart: make(map[string]time.Time),
+}
+
+// SetMaxVoices ограничивает число одновременно звучащих звуков.
+// max <= 0 снимает ограничение.
+func SetMaxVoices(max int, policy VoicePolicy) {
+	limits.Lock()
+	defer limits.Unlock()
+	limits.global = voiceLimit{max: max, policy: policy}
+}
+
+// SetGroupMaxVoices ограничивает число одновременно звучащих звуков
+// с PlayParams.Group == group. max <= 0 снимает ограничение.
+func SetGroupMaxVoices(group string, max int, policy VoicePolicy) {
+	limits.Lock()
+	defer limits.Unlock()
+	if max <= 0 {
+		delete(limits.groups, group)
+		return
+	}
+	limits.groups[group] = voiceLimit{max: max, policy: policy}
+}
+
+// SetSourceLimit задаёт ограничения для источника source — того же пути
+// или URL, что передаётся в PlaySoundWithParams. Нулевой SourceLimit снимает их.
+func SetSourceLimit(source string, limit SourceLimit) {
+	limits.Lock()
+	defer limits.Unlock()
+	if limit.MaxInstances <= 0 && limit.MinInterval <= 0 {
+		delete(limits.sources, source)
+		delete(limits.lastStart, source)
+		return
+	}
+	limits.sources[source] = limit
+}
+
+// retrigger применяет лимит источника до того, как файл будет открыт.
+// Если вместо нового звука перезапущен существующий, возвращает его канал.
+// nil без ошибки означает, что новый экземпляр можно запускать.
+func retrigger(source string) (chan struct{}, error) {
+	limits.Lock()
+	limit, ok := limits.sources[source]
+	limits.Unlock()
+	if !ok || limit.Policy == RetriggerStack {
+		return nil, nil
+	}
+
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	return applySourceLimit(source, limit)
+}
+
+// applySourceLimit проверяет лимит экземпляров и интервал запуска источника
+// с политикой RetriggerSkip или RetriggerRestart. Вызывается под activeMu:
+// admitVoice повторяет проверку вместе с регистрацией звука, поэтому
+// параллельные запуски не проходят её все разом.
+func applySourceLimit(source string, limit SourceLimit) (chan struct{}, error) {
+	limits.Lock()
+	last := limits.lastStart[source]
+	limits.Unlock()
+
+	instances := sourceInstances(source)
+	full := limit.MaxInstances > 0 && len(instances) >= limit.MaxInstances
+	tooSoon := limit.MinInterval > 0 && time.Since(last) < limit.MinInterval
+	if !full && !tooSoon {
+		return nil, nil
+	}
+
+	if limit.Policy == RetriggerSkip {
+		return nil, fmt.Errorf("sound %q skipped by source limit", source)
+	}
+	if len(instances) == 0 {
+		// Предыдущий экземпляр уже доиграл, перезапускать нечего
+		return nil, nil
+	}
+	// При лимите экземпляров перезапускаем самый старый, иначе — последний запущенный
+	done := instances[len(instances)-1]
+	if full {
+		done = instances[0]
+	}
+	if err := restartSound(done); err != nil {
+		return nil, err
+	}
+	limits.Lock()
+	limits.lastStart[source] = time.Now()
+	limits.Unlock()
+	return done, nil
+}
+
+// sourceInstances возвращает каналы активных звуков источника в порядке запуска.
+// Звуки, затухающие перед остановкой, уже не считаются. Вызывается под activeMu.
+func sourceInstances(source string) []chan struct{} {
+	var dones []chan struct{}
+	for done, sc := range activeSounds {
+		if sc.source == source && !sc.stopping() {
+			dones = append(dones, done)
+		}
+	}
+	sort.Slice(dones, func(i, j int) bool { return activeSounds[dones[i]].seq < activeSounds[dones[j]].seq })
+	return dones
+}
+
+// restartSound перематывает звук на стартовую позицию и снимает с паузы,
+// прерывая плавное изменение громкости. Остановленный или затухающий перед
+// остановкой звук не перезапускается. Вызывается под activeMu.
+func restartSound(done chan struct{}) error {
+	sc := activeSounds[done]
+	if sc.stopping() {
+		return &TransitionError{From: sc.State(), To: StatePlaying}
+	}
+
+	var err error
+	switch {
+	case sc.params.Position > 0:
+		_, err = sc.player.Seek(secondsToBytes(sc.params.Position, sc.sampleRate), io.SeekStart)
+	case sc.params.Reverse:
+		_, err = sc.player.Seek(0, io.SeekEnd)
+	default:
+		_, err = sc.player.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = sc.switchTo(StatePlaying, 0, func() {
+		sc.player.SetVolume(sc.volume)
+		sc.player.Play()
+	})
+	var terr *TransitionError
+	if errors.As(err, &terr) && terr.From == StatePlaying {
+		return nil // Играющий звук достаточно перемотать
+	}
+	return err
+}
+
+// admitVoice проверяет лимит источника, лимиты групп и общий лимит для нового звука.
+// Возвращает звуки, которые нужно вытеснить, чтобы освободить место. Если по
+// политике RetriggerRestart вместо нового звука перезапущен существующий,
+// возвращает его канал. Вызывается под activeMu.
+func admitVoice(source string, params PlayParams) (map[chan struct{}]*soundController, chan struct{}, error) {
+	limits.Lock()
+	global := limits.global
+	group, hasGroup := limits.groups[params.Group]
+	perSource, hasSource := limits.sources[source]
+	limits.Unlock()
+
+	victims := make(map[chan struct{}]*soundController)
+	switch {
+	case hasSource && perSource.Policy != RetriggerStack:
+		// retrigger уже проверял лимит, но параллельные запуски могли пройти
+		// проверку все разом, пока ни один из них не был зарегистрирован.
+		if done, err := applySourceLimit(source, perSource); done != nil || err != nil {
+			return nil, done, err
+		}
+	case hasSource && perSource.MaxInstances > 0:
+		limit := voiceLimit{max: perSource.MaxInstances, policy: StealOldest}
+		fromSource := func(sc *soundController) bool { return sc.source == source }
+		if !pickVictims(limit, params, victims, fromSource) {
+			return nil, nil, fmt.Errorf("sound %q skipped by source limit", source)
+		}
+	}
+	if hasGroup {
+		inGroup := func(sc *soundController) bool { return sc.params.Group == params.Group }
+		if !pickVictims(group, params, victims, inGroup) {
+			return nil, nil, fmt.Errorf("voice limit for group %q reached", params.Group)
+		}
+	}
+	if global.max > 0 {
+		if !pickVictims(global, params, victims, nil) {
+			return nil, nil, fmt.Errorf("voice limit reached")
+		}
+	}
+	if hasSource {
+		limits.Lock()
+		limits.lastStart[source] = time.Now()
+		limits.Unlock()
+	}
+	return victims, nil, nil
+}
+
+// pickVictims добавляет в victims звуки, подходящие под match, пока их
+// число (без уже выбранных жертв) не станет меньше лимита. Звуки, затухающие
+// перед остановкой, как и в sourceInstances, места не занимают и не вытесняются.
+// Возвращает false, если по политике места для нового звука нет.
+func pickVictims(limit voiceLimit, params PlayParams, victims map[chan struct{}]*soundController, match func(*soundController) bool) bool {
+	candidate := func(done chan struct{}, sc *soundController) bool {
+		_, stolen := victims[done]
+		return !stolen && (match == nil || match(sc)) && !sc.stopping()
+	}
+
+	count := 0
+	for done, sc := range activeSounds {
+		if candidate(done, sc) {
+			count++
+		}
+	}
+
+	for ; count >= limit.max; count-- {
+		if limit.policy == RejectNew {
+			return false
+		}
+
+		var victim chan struct{}
+		var best *soundController
+		for done, sc := range activeSounds {
+			if !candidate(done, sc) {
+				continue
+			}
+			if victim == nil || betterVictim(limit.policy, sc, best) {
+				victim, best = done, sc
+			}
+		}
+		// Звук с более высоким приоритетом не уступает место менее важному
+		if victim == nil || (limit.policy == StealLowestPriority && best.params.Priority > params.Priority) {
+			return false
+		}
+		victims[victim] = best
+	}
+	return true
+}
+
+// betterVictim сообщает, лучше ли a подходит для вытеснения, чем b.
+// При равенстве вытесняется более старый звук.
+func betterVictim(policy VoicePolicy, a, b *soundController) bool {
+	switch policy {
+	case StealQuietest:
+		if va, vb := voiceVolume(a), voiceVolume(b); va != vb {
+			return va < vb
+		}
+	case StealLowestPriority:
+		if a.params.Priority != b.params.Priority {
+			return a.params.Priority < b.params.Priority
+		}
+	}
+	return a.seq < b.seq
+}
+
+// voiceVolume возвращает итоговую громкость голоса с учётом нормализации.
+func voiceVolume(sc *soundController) float64 {
+	if sc.player == nil {
+		return 0
+	}
+	sc.player.mu.Lock()
+	defer sc.player.mu.Unlock()
+	return sc.player.volume * sc.player.gain * sc.player.trim
+}
+
+// stealVoices мгновенно останавливает вытесненные звуки, без FadeOut.
+// Звук убирается из activeSounds, и мониторинг освобождает его ресурсы.
+// Вызывается под activeMu.
+func stealVoices(victims map[chan struct{}]*soundController) {
+	for done, sc := range victims {
+		sc.stopped()
+		delete(activeSounds, done)
+		if sc.player != nil {
+			sc.player.SetVolume(0)
+			sc.player.Pause()
+		}
+		if sc.cancel != nil {
+			sc.cancel()
+		}
+	}
+}
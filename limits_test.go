@@ -0,0 +1,296 @@
+package playsound
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withSounds регистрирует фиктивные звуки и снимает их и лимиты после теста.
+func withSounds(t *testing.T, sounds ...*soundController) []chan struct{} {
+	t.Helper()
+	dones := make([]chan struct{}, len(sounds))
+	activeMu.Lock()
+	for i, sc := range sounds {
+		dones[i] = make(chan struct{})
+		activeSounds[dones[i]] = sc
+	}
+	activeMu.Unlock()
+
+	t.Cleanup(func() {
+		activeMu.Lock()
+		for _, done := range dones {
+			delete(activeSounds, done)
+		}
+		activeMu.Unlock()
+		SetMaxVoices(0, RejectNew)
+		limits.Lock()
+		clear(limits.groups)
+		clear(limits.sources)
+		clear(limits.lastStart)
+		limits.Unlock()
+	})
+	return dones
+}
+
+func admit(source string, params PlayParams) (map[chan struct{}]*soundController, error) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	victims, _, err := admitVoice(source, params)
+	return victims, err
+}
+
+func TestVoiceLimitPolicies(t *testing.T) {
+	quiet := newVoice(nil)
+	quiet.SetVolume(0.2)
+	loud := newVoice(nil)
+	dones := withSounds(t,
+		&soundController{seq: 1, player: loud, params: PlayParams{Priority: 5}},
+		&soundController{seq: 2, player: quiet, params: PlayParams{Priority: 1}},
+		&soundController{seq: 3, player: loud, params: PlayParams{Priority: 1}},
+	)
+
+	tests := []struct {
+		policy VoicePolicy
+		want   chan struct{}
+	}{
+		{StealOldest, dones[0]},
+		{StealQuietest, dones[1]},
+		{StealLowestPriority, dones[1]},
+	}
+	for _, tt := range tests {
+		SetMaxVoices(3, tt.policy)
+		victims, err := admit("", PlayParams{Priority: 1})
+		if err != nil {
+			t.Fatalf("policy %d: %v", tt.policy, err)
+		}
+		if _, ok := victims[tt.want]; !ok || len(victims) != 1 {
+			t.Errorf("policy %d: victims = %v; want %v", tt.policy, victims, tt.want)
+		}
+	}
+
+	SetMaxVoices(3, RejectNew)
+	if _, err := admit("", PlayParams{}); err == nil {
+		t.Error("RejectNew: expected error")
+	}
+
+	// Менее важный звук не вытесняет более важные
+	SetMaxVoices(1, StealLowestPriority)
+	if _, err := admit("", PlayParams{Priority: 0}); err == nil {
+		t.Error("StealLowestPriority: low priority sound should be rejected")
+	}
+
+	SetMaxVoices(4, RejectNew)
+	if victims, err := admit("", PlayParams{}); err != nil || len(victims) != 0 {
+		t.Errorf("under limit: victims = %v, err = %v", victims, err)
+	}
+}
+
+// Звук, затухающий перед остановкой, не занимает место и не вытесняется повторно
+func TestVoiceLimitSkipsStopping(t *testing.T) {
+	dones := withSounds(t,
+		&soundController{seq: 1, state: StateFading, fadeTo: StateStopped},
+		&soundController{seq: 2, state: StatePlaying},
+	)
+
+	SetMaxVoices(2, RejectNew)
+	if victims, err := admit("", PlayParams{}); err != nil || len(victims) != 0 {
+		t.Errorf("RejectNew: victims = %v, err = %v; want a free slot", victims, err)
+	}
+	SetMaxVoices(1, StealOldest)
+	victims, err := admit("", PlayParams{})
+	if _, ok := victims[dones[1]]; err != nil || !ok || len(victims) != 1 {
+		t.Errorf("StealOldest: victims = %v, err = %v; want only the live sound", victims, err)
+	}
+}
+
+func TestGroupVoiceLimit(t *testing.T) {
+	dones := withSounds(t,
+		&soundController{seq: 1, params: PlayParams{Group: "music"}},
+		&soundController{seq: 2, params: PlayParams{Group: "ui"}},
+		&soundController{seq: 3, params: PlayParams{Group: "ui"}},
+	)
+
+	SetGroupMaxVoices("ui", 2, StealOldest)
+	victims, err := admit("", PlayParams{Group: "ui"})
+	if _, ok := victims[dones[1]]; err != nil || !ok || len(victims) != 1 {
+		t.Errorf("ui: victims = %v, err = %v; want oldest ui sound", victims, err)
+	}
+	if victims, err := admit("", PlayParams{Group: "music"}); err != nil || len(victims) != 0 {
+		t.Errorf("music: victims = %v, err = %v", victims, err)
+	}
+
+	// Общий лимит учитывает звуки, уже вытесненные лимитом группы
+	SetMaxVoices(3, StealOldest)
+	victims, err = admit("", PlayParams{Group: "ui"})
+	if err != nil || len(victims) != 1 {
+		t.Errorf("group and global: victims = %v, err = %v; want one", victims, err)
+	}
+
+	SetGroupMaxVoices("ui", 2, RejectNew)
+	if _, err := admit("", PlayParams{Group: "ui"}); err == nil {
+		t.Error("ui RejectNew: expected error")
+	}
+}
+
+func TestStealVoices(t *testing.T) {
+	player := newVoice(nil)
+	player.Play()
+	cancelled := false
+	dones := withSounds(t, &soundController{seq: 1, player: player, cancel: func() { cancelled = true }})
+
+	activeMu.Lock()
+	stealVoices(map[chan struct{}]*soundController{dones[0]: activeSounds[dones[0]]})
+	activeMu.Unlock()
+
+	if _, ok := getControl(dones[0]); ok {
+		t.Error("stolen sound should be removed from activeSounds")
+	}
+	if player.IsPlaying() || player.Volume() != 0 || !cancelled {
+		t.Errorf("stolen voice: playing %v, volume %v, cancelled %v", player.IsPlaying(), player.Volume(), cancelled)
+	}
+}
+
+func TestSourceLimit(t *testing.T) {
+	tracker := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(make([]byte, 4000)), 1000}}
+	player := newVoice(tracker)
+	player.Seek(2000, 0)
+	dones := withSounds(t,
+		&soundController{seq: 1, source: "click.wav", player: player, tracker: tracker, sampleRate: 1000, state: StatePaused, params: PlayParams{Volume: 0.7}, volume: 0.7},
+		&soundController{seq: 2, source: "click.wav"},
+		&soundController{seq: 3, source: "other.wav"},
+	)
+
+	// Без лимита источник запускается как обычно
+	if done, err := retrigger("click.wav"); done != nil || err != nil {
+		t.Errorf("no limit: retrigger() = %v, %v", done, err)
+	}
+
+	SetSourceLimit("click.wav", SourceLimit{MaxInstances: 2, Policy: RetriggerSkip})
+	if _, err := retrigger("click.wav"); err == nil {
+		t.Error("RetriggerSkip: expected error")
+	}
+	if done, err := retrigger("other.wav"); done != nil || err != nil {
+		t.Errorf("other source: retrigger() = %v, %v", done, err)
+	}
+
+	SetSourceLimit("click.wav", SourceLimit{MaxInstances: 2, Policy: RetriggerRestart})
+	done, err := retrigger("click.wav")
+	if err != nil || done != dones[0] {
+		t.Fatalf("RetriggerRestart: retrigger() = %v, %v; want oldest instance", done, err)
+	}
+	ctrl, _ := getControl(dones[0])
+	if tracker.CurrentPos() != 0 || !player.IsPlaying() || ctrl.State() != StatePlaying || player.Volume() != 0.7 {
+		t.Errorf("restarted: pos %d, playing %v, state %v, volume %v", tracker.CurrentPos(), player.IsPlaying(), ctrl.State(), player.Volume())
+	}
+
+	SetSourceLimit("click.wav", SourceLimit{MaxInstances: 2, Policy: RetriggerStack})
+	if done, err := retrigger("click.wav"); done != nil || err != nil {
+		t.Errorf("RetriggerStack: retrigger() = %v, %v", done, err)
+	}
+	victims, err := admit("click.wav", PlayParams{})
+	if _, ok := victims[dones[0]]; err != nil || !ok || len(victims) != 1 {
+		t.Errorf("RetriggerStack: victims = %v, err = %v; want oldest instance", victims, err)
+	}
+
+	SetSourceLimit("click.wav", SourceLimit{})
+	if done, err := retrigger("click.wav"); done != nil || err != nil {
+		t.Errorf("limit removed: retrigger() = %v, %v", done, err)
+	}
+}
+
+func TestSourceRetriggerInterval(t *testing.T) {
+	withSounds(t)
+	SetSourceLimit("click.wav", SourceLimit{MinInterval: time.Hour, Policy: RetriggerSkip})
+
+	if _, err := retrigger("click.wav"); err != nil {
+		t.Fatalf("first trigger: %v", err)
+	}
+	if _, err := admit("click.wav", PlayParams{}); err != nil {
+		t.Fatalf("first start: %v", err)
+	}
+	if _, err := retrigger("click.wav"); err == nil {
+		t.Error("retrigger within MinInterval should be skipped")
+	}
+
+	// Перезапускать нечего — новый экземпляр запускается
+	SetSourceLimit("click.wav", SourceLimit{MinInterval: time.Hour, Policy: RetriggerRestart})
+	if done, err := retrigger("click.wav"); done != nil || err != nil {
+		t.Errorf("RetriggerRestart without instances: retrigger() = %v, %v", done, err)
+	}
+}
+
+// Параллельные запуски одного источника не проходят интервал все разом:
+// RetriggerSkip запускает один звук, RetriggerRestart возвращает всем его канал
+func TestSourceRetriggerConcurrent(t *testing.T) {
+	withSounds(t)
+	defer StopAllWithOptions(0, true)
+	path := writeTestWAV(t, sinePCM(440, 8000, 1), 8000)
+
+	start := func() (map[chan struct{}]int, int) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		started := make(map[chan struct{}]int)
+		failed := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				done, err := PlaySoundWithParams(path, PlayParams{})
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failed++
+				} else {
+					started[done]++
+				}
+			}()
+		}
+		wg.Wait()
+		return started, failed
+	}
+
+	SetSourceLimit(path, SourceLimit{MinInterval: time.Hour, Policy: RetriggerSkip})
+	if started, failed := start(); len(started) != 1 || failed != 7 {
+		t.Errorf("RetriggerSkip: %d sounds started, %d skipped; want 1 and 7", len(started), failed)
+	}
+
+	StopAllWithOptions(0, true)
+	SetSourceLimit(path, SourceLimit{})
+	SetSourceLimit(path, SourceLimit{MinInterval: time.Hour, Policy: RetriggerRestart})
+	if started, failed := start(); len(started) != 1 || failed != 0 {
+		t.Errorf("RetriggerRestart: %d sounds, %d errors; want one shared sound", len(started), failed)
+	}
+}
+
+// Звук, затухающий перед остановкой, не перезапускается: вместо него
+// запускается новый экземпляр
+func TestRestartSkipsStoppingSound(t *testing.T) {
+	tracker := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(make([]byte, 4000)), 1000}}
+	player := newVoice(tracker)
+	player.Play()
+	stopping := &soundController{seq: 1, source: "click.wav", player: player, tracker: tracker, state: StateFading, fadeTo: StateStopped, volume: 1}
+	playing := &soundController{seq: 2, source: "loop.wav", player: newVoice(tracker), tracker: tracker, state: StatePlaying, volume: 1}
+	dones := withSounds(t, stopping, playing)
+
+	SetSourceLimit("click.wav", SourceLimit{MaxInstances: 1, Policy: RetriggerRestart})
+	if done, err := retrigger("click.wav"); done != nil || err != nil {
+		t.Errorf("retrigger() = %v, %v; want a new instance", done, err)
+	}
+	if stopping.State() != StateFading || stopping.fadeTo != StateStopped {
+		t.Errorf("stopping sound: state %v, fadeTo %v; want fading to stopped", stopping.State(), stopping.fadeTo)
+	}
+	activeMu.Lock()
+	err := restartSound(dones[0])
+	activeMu.Unlock()
+	if err == nil {
+		t.Error("restartSound() should refuse a sound that is stopping")
+	}
+
+	// Играющий звук просто перематывается
+	SetSourceLimit("loop.wav", SourceLimit{MaxInstances: 1, Policy: RetriggerRestart})
+	if done, err := retrigger("loop.wav"); done != dones[1] || err != nil || playing.State() != StatePlaying {
+		t.Errorf("retrigger(playing) = %v, %v, state %v; want restarted", done, err, playing.State())
+	}
+}
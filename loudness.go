@@ -0,0 +1,271 @@
+package playsound
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTargetLUFS   = -18.0 // Целевая громкость по умолчанию (эталон ReplayGain 2.0)
+	replayGainReference = -18.0 // Громкость, относительно которой записаны теги ReplayGain
+	maxNormalizeBoost   = 12.0  // Максимальное усиление при нормализации, дБ
+	minNormalizeGain    = -30.0 // Максимальное ослабление при нормализации, дБ
+
+	normalizeRamp = 200 * time.Millisecond // За сколько применяется усиление, измеренное после запуска
+
+	// Параметры стробирования EBU R128 / ITU-R BS.1770.
+	loudnessBlockSegments = 4     // Блок 400 мс из четырёх сегментов по 100 мс (перекрытие 75%)
+	loudnessAbsoluteGate  = -70.0 // Абсолютный порог, LUFS
+	loudnessRelativeGate  = -10.0 // Относительный порог, LU
+)
+
+// loudnessCache хранит измеренную громкость треков, чтобы не декодировать их повторно.
+var loudnessCache = struct {
+	sync.Mutex
+	values map[string]float64
+}{values: make(map[string]float64)}
+
+// loudnessCacheKey строит ключ кеша: для локальных файлов учитываются размер
+// и время изменения, чтобы перезаписанный файл измерялся заново.
+func loudnessCacheKey(source string) string {
+	if info, err := os.Stat(source); err == nil {
+		return fmt.Sprintf("%s|%d|%d", source, info.Size(), info.ModTime().UnixNano())
+	}
+	return source
+}
+
+// MeasureLoudness возвращает интегральную громкость трека в LUFS по EBU R128.
+// Для этого трек декодируется целиком: у длинного файла или URL это секунды.
+// Результат кешируется. Звук с Normalize без тега ReplayGain и без значения
+// в кеше начинает играть без нормализации, а усиление плавно применяется,
+// когда фоновое измерение закончится. Чтобы громкость была верной с первого
+// семпла, вызовите MeasureLoudness заранее.
+func MeasureLoudness(source string) (float64, error) {
+	key := loudnessCacheKey(source)
+	loudnessCache.Lock()
+	lufs, ok := loudnessCache.values[key]
+	loudnessCache.Unlock()
+	if ok {
+		return lufs, nil
+	}
+
+	rs, closer, err := getReadSeeker(source)
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+
+	stream, err := getDecoder(rs, source)
+	if err != nil {
+		return 0, err
+	}
+	return measureAndCache(key, stream)
+}
+
+// measureAndCache измеряет громкость потока и сохраняет её в кеше.
+func measureAndCache(key string, stream decodedStream) (float64, error) {
+	lufs, err := integratedLoudness(stream)
+	if err != nil {
+		return 0, err
+	}
+
+	loudnessCache.Lock()
+	loudnessCache.values[key] = lufs
+	loudnessCache.Unlock()
+	return lufs, nil
+}
+
+// kWeighting возвращает два фильтра K-взвешивания по ITU-R BS.1770: полку +4 дБ
+// на верхах и срез ниже ~38 Гц. Коэффициенты рассчитываются как в libebur128,
+// что для 48 кГц точно совпадает с таблицей стандарта.
+func kWeighting(sampleRate int) (*biquad, *biquad) {
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+		hpFreq    = 38.13547087602444
+		hpQ       = 0.5003270373238773
+	)
+	rate := float64(sampleRate)
+
+	k := math.Tan(math.Pi * shelfFreq / rate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := &biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * hpFreq / rate)
+	a0 = 1 + k/hpQ + k*k
+	highPass := &biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/hpQ + k*k) / a0,
+	}
+	return shelf, highPass
+}
+
+// integratedLoudness декодирует поток целиком и считает интегральную громкость:
+// K-фильтр, среднеквадратичное по блокам 400 мс и двухступенчатое стробирование.
+func integratedLoudness(stream decodedStream) (float64, error) {
+	rate := stream.SampleRate()
+	segmentFrames := rate / 10
+	if segmentFrames <= 0 {
+		return 0, fmt.Errorf("invalid sample rate %d", rate)
+	}
+
+	shelf, highPass := kWeighting(rate)
+
+	raw := make([]byte, segmentFrames*frameBytes)
+	frames := make([]float32, segmentFrames*2)
+	var segments []float64 // Сумма квадратов обоих каналов за сегмент
+
+	for {
+		n, err := io.ReadFull(stream, raw)
+		got := n / frameBytes
+		if got > 0 {
+			buf := frames[:got*2]
+			bytesToFloats(raw[:got*frameBytes], buf)
+			shelf.process(buf)
+			highPass.process(buf)
+
+			var sum float64
+			for _, v := range buf {
+				sum += float64(v) * float64(v)
+			}
+			// Неполный последний сегмент учитываем пропорционально
+			segments = append(segments, sum*float64(segmentFrames)/float64(got))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	blockLoudness := func(power float64) float64 {
+		return -0.691 + 10*math.Log10(power)
+	}
+
+	var blocks []float64 // Средняя мощность каждого блока
+	for i := 0; i+loudnessBlockSegments <= len(segments); i++ {
+		var sum float64
+		for _, s := range segments[i : i+loudnessBlockSegments] {
+			sum += s
+		}
+		blocks = append(blocks, sum/float64(segmentFrames*loudnessBlockSegments))
+	}
+	if len(blocks) == 0 && len(segments) > 0 {
+		// Трек короче одного блока — измеряем его целиком
+		var sum float64
+		for _, s := range segments {
+			sum += s
+		}
+		blocks = append(blocks, sum/float64(segmentFrames*len(segments)))
+	}
+
+	gatedMean := func(threshold float64) (float64, int) {
+		var sum float64
+		var n int
+		for _, p := range blocks {
+			if p > 0 && blockLoudness(p) > threshold {
+				sum += p
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, 0
+		}
+		return sum / float64(n), n
+	}
+
+	mean, n := gatedMean(loudnessAbsoluteGate)
+	if n == 0 {
+		return math.Inf(-1), nil // Тишина
+	}
+	mean, n = gatedMean(blockLoudness(mean) + loudnessRelativeGate)
+	if n == 0 {
+		return math.Inf(-1), nil
+	}
+	return blockLoudness(mean), nil
+}
+
+// parseReplayGain разбирает значение вида "-6.54 dB".
+func parseReplayGain(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(value, "dB"), "DB"))
+	gain, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return gain, true
+}
+
+// normalizationGain рассчитывает линейный коэффициент, приводящий трек к громкости target,
+// по тегу ReplayGain или измерению из кеша. measured = false, если ни того ни другого
+// нет: тогда возвращается 1, а громкость нужно измерить (см. normalizeLater).
+func normalizationGain(source string, target, replayGain float64, hasReplayGain bool) (gain float64, measured bool) {
+	if hasReplayGain {
+		return clampNormalizeGain(replayGain + target - replayGainReference), true
+	}
+
+	loudnessCache.Lock()
+	lufs, ok := loudnessCache.values[loudnessCacheKey(source)]
+	loudnessCache.Unlock()
+	if !ok {
+		return 1, false
+	}
+	return loudnessGain(lufs, target), true
+}
+
+// loudnessGain возвращает коэффициент, приводящий громкость lufs к target.
+// Тишина не усиливается.
+func loudnessGain(lufs, target float64) float64 {
+	if math.IsInf(lufs, -1) {
+		return 1
+	}
+	return clampNormalizeGain(target - lufs)
+}
+
+// clampNormalizeGain ограничивает усиление нормализации и переводит его из дБ в разы.
+func clampNormalizeGain(gainDB float64) float64 {
+	gainDB = math.Min(math.Max(gainDB, minNormalizeGain), maxNormalizeBoost)
+	return dbToLinear(gainDB)
+}
+
+// measureStream измеряет громкость уже открытого потока, кеширует её
+// и перематывает поток в начало. Так нормализует рендеринг, которому
+// дождаться измерения важнее, чем начать сразу.
+func measureStream(source string, stream decodedStream, target float64) (float64, error) {
+	lufs, err := measureAndCache(loudnessCacheKey(source), stream)
+	if err != nil {
+		return 1, err
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		return 1, err
+	}
+	return loudnessGain(lufs, target), nil
+}
+
+// normalizeLater измеряет громкость трека в фоне и за normalizeRamp плавно
+// выводит голос на усиление нормализации. Пока идёт измерение, звук играет
+// без неё; если измерить не удалось, так и остаётся.
+func normalizeLater(source string, target float64, v *voice, sampleRate int) {
+	lufs, err := MeasureLoudness(source)
+	if err != nil {
+		return
+	}
+	v.rampGain(loudnessGain(lufs, target), int(normalizeRamp.Seconds()*float64(sampleRate)))
+}
@@ -0,0 +1,191 @@
+package playsound
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLimiterCeiling = -0.1                  // Порог лимитера по умолчанию, дБFS
+	limiterRelease        = 50 * time.Millisecond // Время восстановления лимитера
+)
+
+// masterBus — мастер-обработка шины микшера: компрессор (по желанию) и лимитер.
+// Поля защищены мьютексом микшера.
+type masterBus struct {
+	compressor *Compressor
+	limiter    *Limiter
+}
+
+func (mb *masterBus) process(frames []float32, sampleRate int) {
+	if mb.compressor != nil {
+		mb.compressor.Process(frames, sampleRate)
+	}
+	if mb.limiter != nil {
+		mb.limiter.Process(frames, sampleRate)
+	}
+}
+
+// CompressorParams задаёт параметры компрессора.
+type CompressorParams struct {
+	Threshold  float64       // Порог срабатывания, дБFS (например, -12)
+	Ratio      float64       // Степень сжатия выше порога (4 означает 4:1)
+	Attack     time.Duration // Время реакции на превышение порога
+	Release    time.Duration // Время восстановления после снижения уровня
+	MakeupGain float64       // Компенсирующее усиление после сжатия, дБ
+}
+
+// Compressor уменьшает динамический диапазон сигнала выше порога.
+// Оба канала управляются общим уровнем, чтобы не смещать стереопанораму.
+type Compressor struct {
+	mu     sync.Mutex
+	params CompressorParams
+	env    float64 // Текущее ослабление в дБ
+}
+
+// NewCompressor создаёт компрессор с заданными параметрами.
+func NewCompressor(params CompressorParams) *Compressor {
+	return &Compressor{params: clampCompressor(params)}
+}
+
+func clampCompressor(p CompressorParams) CompressorParams {
+	if p.Ratio < 1 {
+		p.Ratio = 1
+	}
+	if p.Threshold > 0 {
+		p.Threshold = 0
+	}
+	return p
+}
+
+// SetParams меняет параметры компрессора во время воспроизведения.
+func (c *Compressor) SetParams(params CompressorParams) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.params = clampCompressor(params)
+}
+
+// Params возвращает текущие параметры.
+func (c *Compressor) Params() CompressorParams {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.params
+}
+
+// Process реализует Processor.
+func (c *Compressor) Process(frames []float32, sampleRate int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	attack := smoothingCoef(c.params.Attack, sampleRate)
+	release := smoothingCoef(c.params.Release, sampleRate)
+	slope := 1 - 1/c.params.Ratio
+
+	for i := 0; i+1 < len(frames); i += 2 {
+		level := linearToDB(math.Max(math.Abs(float64(frames[i])), math.Abs(float64(frames[i+1]))))
+
+		var target float64
+		if over := level - c.params.Threshold; over > 0 {
+			target = over * slope
+		}
+		if target > c.env {
+			c.env = attack*c.env + (1-attack)*target
+		} else {
+			c.env = release*c.env + (1-release)*target
+		}
+
+		gain := float32(dbToLinear(c.params.MakeupGain - c.env))
+		frames[i] *= gain
+		frames[i+1] *= gain
+	}
+}
+
+// Limiter — «кирпичный» лимитер: мгновенно ослабляет пики выше порога,
+// поэтому выход никогда не превышает Ceiling и не переполняет int16.
+type Limiter struct {
+	mu      sync.Mutex
+	ceiling float64 // Порог в дБFS
+	gain    float64 // Текущий коэффициент усиления (1 — без ослабления)
+}
+
+// NewLimiter создаёт лимитер с порогом ceiling в дБFS (например, -0.1).
+func NewLimiter(ceiling float64) *Limiter {
+	return &Limiter{ceiling: math.Min(ceiling, 0), gain: 1}
+}
+
+// SetCeiling меняет порог лимитера.
+func (l *Limiter) SetCeiling(ceiling float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ceiling = math.Min(ceiling, 0)
+}
+
+// Ceiling возвращает порог лимитера в дБFS.
+func (l *Limiter) Ceiling() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ceiling
+}
+
+// Process реализует Processor.
+func (l *Limiter) Process(frames []float32, sampleRate int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ceiling := dbToLinear(l.ceiling)
+	release := smoothingCoef(limiterRelease, sampleRate)
+
+	for i := 0; i+1 < len(frames); i += 2 {
+		peak := math.Max(math.Abs(float64(frames[i])), math.Abs(float64(frames[i+1])))
+
+		target := 1.0
+		if peak > ceiling {
+			target = ceiling / peak
+		}
+		if target < l.gain {
+			l.gain = target
+		} else {
+			l.gain = release*l.gain + (1-release)*target
+		}
+
+		frames[i] = float32(float64(frames[i]) * l.gain)
+		frames[i+1] = float32(float64(frames[i+1]) * l.gain)
+	}
+}
+
+// smoothingCoef переводит постоянную времени в коэффициент однополюсного сглаживания.
+func smoothingCoef(d time.Duration, sampleRate int) float64 {
+	if d <= 0 || sampleRate <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (d.Seconds() * float64(sampleRate)))
+}
+
+func linearToDB(v float64) float64 {
+	if v <= 1e-10 {
+		return -200
+	}
+	return 20 * math.Log10(v)
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// SetMasterCompressor включает компрессор на общей шине; nil отключает его.
+// Компрессор стоит перед лимитером.
+func SetMasterCompressor(c *Compressor) {
+	masterMixer.mu.Lock()
+	defer masterMixer.mu.Unlock()
+	masterMixer.master.compressor = c
+}
+
+// SetMasterLimiter заменяет лимитер общей шины; nil отключает его.
+// По умолчанию включён лимитер с порогом -0.1 дБFS, защищающий от клиппинга
+// при наложении нескольких громких звуков.
+func SetMasterLimiter(l *Limiter) {
+	masterMixer.mu.Lock()
+	defer masterMixer.mu.Unlock()
+	masterMixer.master.limiter = l
+}
@@ -0,0 +1,250 @@
+package playsound
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sync"
+	"time"
+)
+
+const (
+	meterWindow          = 2048                  // Окно анализа в кадрах (степень двойки для БПФ)
+	defaultMeterInterval = 50 * time.Millisecond // Период рассылки по умолчанию (20 кадров в секунду)
+	minMeterInterval     = 5 * time.Millisecond
+)
+
+// Levels — уровни сигнала в дБFS за последнее окно анализа (0 — полная шкала).
+type Levels struct {
+	PeakLeft, PeakRight float64
+	RMSLeft, RMSRight   float64
+}
+
+// MeterFrame — снимок измерителя: уровни и спектр.
+type MeterFrame struct {
+	Levels   Levels
+	Spectrum []float64 // Амплитуды в дБFS; i-я полоса соответствует частоте i * BinWidth
+	BinWidth float64   // Ширина полосы спектра в Гц
+}
+
+// meter хранит последние кадры, прошедшие через звук или шину.
+// Путь воспроизведения только копирует кадры в кольцевой буфер,
+// а уровни и спектр считаются по запросу в горутине получателя.
+type meter struct {
+	mu         sync.Mutex
+	ring       []float32 // Последние meterWindow стереокадров
+	pos        int       // Куда будет записан следующий кадр
+	sampleRate int
+	done       chan struct{} // Закрывается, когда звук завершился
+	closed     bool
+}
+
+// write добавляет обработанные кадры в кольцевой буфер.
+func (m *meter) write(frames []float32, sampleRate int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ring == nil {
+		m.ring = make([]float32, meterWindow*2)
+	}
+	m.sampleRate = sampleRate
+	// Из длинного буфера нужны только последние meterWindow кадров
+	if len(frames) > len(m.ring) {
+		frames = frames[len(frames)-len(m.ring):]
+	}
+	for len(frames) > 0 {
+		n := copy(m.ring[m.pos*2:], frames)
+		frames = frames[n:]
+		m.pos = (m.pos + n/2) % meterWindow
+	}
+}
+
+// window возвращает копию окна анализа в хронологическом порядке.
+func (m *meter) window() ([]float32, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]float32, meterWindow*2)
+	if m.ring != nil {
+		n := copy(out, m.ring[m.pos*2:])
+		copy(out[n:], m.ring[:m.pos*2])
+	}
+	return out, m.sampleRate
+}
+
+// frame считает уровни и спектр по текущему окну.
+func (m *meter) frame() MeterFrame {
+	frames, rate := m.window()
+	spectrum := spectrumOf(frames)
+
+	var f MeterFrame
+	f.Levels = levelsOf(frames)
+	f.Spectrum = spectrum
+	if rate > 0 {
+		f.BinWidth = float64(rate) / meterWindow
+	}
+	return f
+}
+
+// doneChan возвращает канал, закрываемый по окончании звука.
+func (m *meter) doneChan() chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.done == nil {
+		m.done = make(chan struct{})
+		if m.closed {
+			close(m.done)
+		}
+	}
+	return m.done
+}
+
+// close завершает все подписки измерителя.
+func (m *meter) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.closed = true
+	if m.done != nil {
+		close(m.done)
+	}
+}
+
+// subscribe запускает рассылку снимков с периодом interval, пока не закроется
+// измеритель или stop. Если получатель не успевает читать, снимок отбрасывается.
+func (m *meter) subscribe(interval time.Duration, stop <-chan struct{}) <-chan MeterFrame {
+	if interval <= 0 {
+		interval = defaultMeterInterval
+	}
+	interval = max(interval, minMeterInterval)
+
+	ch := make(chan MeterFrame, 1)
+	done := m.doneChan()
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				select {
+				case ch <- m.frame():
+				default:
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// levelsOf считает пиковый и среднеквадратичный уровни каждого канала.
+func levelsOf(frames []float32) Levels {
+	var peakL, peakR, sumL, sumR float64
+	for i := 0; i+1 < len(frames); i += 2 {
+		l, r := float64(frames[i]), float64(frames[i+1])
+		peakL = math.Max(peakL, math.Abs(l))
+		peakR = math.Max(peakR, math.Abs(r))
+		sumL += l * l
+		sumR += r * r
+	}
+	n := float64(len(frames) / 2)
+	if n == 0 {
+		n = 1
+	}
+	return Levels{
+		PeakLeft:  linearToDB(peakL),
+		PeakRight: linearToDB(peakR),
+		RMSLeft:   linearToDB(math.Sqrt(sumL / n)),
+		RMSRight:  linearToDB(math.Sqrt(sumR / n)),
+	}
+}
+
+// spectrumOf считает амплитудный спектр моно-суммы каналов с окном Ханна.
+// Амплитуда нормирована так, что синус с пиком 1 даёт около 0 дБFS.
+func spectrumOf(frames []float32) []float64 {
+	n := len(frames) / 2
+	buf := make([]complex128, n)
+	var windowSum float64
+	for i := range buf {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		windowSum += w
+		buf[i] = complex(w*float64(frames[i*2]+frames[i*2+1])/2, 0)
+	}
+	fft(buf)
+
+	out := make([]float64, n/2+1)
+	for i := range out {
+		out[i] = linearToDB(2 * cmplx.Abs(buf[i]) / windowSum)
+	}
+	return out
+}
+
+// fft — итеративное БПФ по основанию 2 на месте; длина должна быть степенью двойки.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// GetMeter возвращает текущие уровни и спектр звука (до регулятора громкости).
+func GetMeter(done chan struct{}) (MeterFrame, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return MeterFrame{}, fmt.Errorf("sound not found")
+	}
+	return control.tracker.meter.frame(), nil
+}
+
+// MeterEvents возвращает канал снимков уровней и спектра звука с периодом interval
+// (0 — 50 мс). Звук никогда не ждёт получателя: если канал не прочитан,
+// очередной снимок пропускается. Канал закрывается, когда звук завершается.
+func MeterEvents(done chan struct{}, interval time.Duration) (<-chan MeterFrame, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return nil, fmt.Errorf("sound not found")
+	}
+	return control.tracker.meter.subscribe(interval, nil), nil
+}
+
+// GetMasterMeter возвращает уровни и спектр общей шины после мастер-обработки.
+func GetMasterMeter() MeterFrame {
+	return masterMixer.meter.frame()
+}
+
+// MasterMeterEvents подписывается на снимки общей шины с периодом interval (0 — 50 мс).
+// Возвращает канал и функцию отписки, после вызова которой канал закрывается.
+func MasterMeterEvents(interval time.Duration) (<-chan MeterFrame, func()) {
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel := func() { once.Do(func() { close(stop) }) }
+	return masterMixer.meter.subscribe(interval, stop), cancel
+}
@@ -0,0 +1,77 @@
+package playsound
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Тест уровней: синус с пиком 0.5 даёт -6 дБFS пика и -9 дБFS RMS
+func TestMeterLevels(t *testing.T) {
+	var m meter
+	// Пишем больше окна, чтобы проверить перезапись кольцевого буфера
+	m.write(sineFrames(1000, 48000, meterWindow*3), 48000)
+
+	lv := m.frame().Levels
+	want := Levels{PeakLeft: -6.02, PeakRight: -6.02, RMSLeft: -9.03, RMSRight: -9.03}
+	got := []float64{lv.PeakLeft, lv.PeakRight, lv.RMSLeft, lv.RMSRight}
+	exp := []float64{want.PeakLeft, want.PeakRight, want.RMSLeft, want.RMSRight}
+	for i := range got {
+		if math.Abs(got[i]-exp[i]) > 0.1 {
+			t.Errorf("levels = %+v; want %+v", lv, want)
+			break
+		}
+	}
+}
+
+// Тест спектра: максимум приходится на полосу частоты синуса
+func TestMeterSpectrum(t *testing.T) {
+	const rate, freq = 48000, 3000.0
+	var m meter
+	m.write(sineFrames(freq, rate, meterWindow), rate)
+
+	f := m.frame()
+	if len(f.Spectrum) != meterWindow/2+1 {
+		t.Fatalf("spectrum has %d bins; want %d", len(f.Spectrum), meterWindow/2+1)
+	}
+	best := 0
+	for i, v := range f.Spectrum {
+		if v > f.Spectrum[best] {
+			best = i
+		}
+	}
+	if got := float64(best) * f.BinWidth; math.Abs(got-freq) > f.BinWidth {
+		t.Errorf("spectrum peak at %.0f Hz; want %.0f Hz", got, freq)
+	}
+	if v := f.Spectrum[best]; math.Abs(v-(-6.02)) > 1.5 {
+		t.Errorf("spectrum peak = %.2f dBFS; want about -6 dBFS", v)
+	}
+}
+
+// Тест подписки: запись не блокируется непрочитанным каналом, а канал закрывается вместе с измерителем
+func TestMeterSubscription(t *testing.T) {
+	var m meter
+	events := m.subscribe(time.Millisecond, nil)
+
+	frames := sineFrames(440, 8000, 256)
+	start := time.Now()
+	for i := 0; i < 200; i++ {
+		m.write(frames, 8000)
+		time.Sleep(100 * time.Microsecond)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writes took %v with an unread subscriber", elapsed)
+	}
+
+	if _, ok := <-events; !ok {
+		t.Fatal("expected a buffered meter frame")
+	}
+	m.close()
+	for range events {
+	}
+
+	// Подписка после закрытия сразу получает закрытый канал
+	if _, ok := <-m.subscribe(0, nil); ok {
+		t.Error("subscription after close should be closed")
+	}
+}
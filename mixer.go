@@ -0,0 +1,449 @@
+package playsound
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+// masterBufferDuration — размер буфера единственного плеера oto, проигрывающего микшер.
+// Чем он меньше, тем быстрее слышны изменения громкости, паузы и перемотки.
+const masterBufferDuration = 50 * time.Millisecond
+
+// mixer складывает все активные голоса в общую шину, пропускает её через
+// мастер-обработку (компрессор, лимитер) и отдаёт результат плееру oto.
+type mixer struct {
+	mu         sync.Mutex
+	sampleRate int
+	voices     []*voice
+	master     masterBus
+	player     *oto.Player // Плеер, читающий из микшера; nil, пока движок не запущен
+	mix        []float32
+	meter      meter // Измеритель уровней и спектра шины после мастер-обработки
+	frames     int64 // Сколько кадров шина уже смешала — часы таймлайна микшера
+}
+
+// newMixer создаёт микшер с лимитером на шине.
+// Частота дискретизации задаётся при запуске движка.
+func newMixer() *mixer {
+	m := &mixer{}
+	m.master.limiter = NewLimiter(defaultLimiterCeiling)
+	return m
+}
+
+// attach подключает микшер к контексту oto: создаёт плеер, читающий шину, и запускает его.
+func (m *mixer) attach(ctx *oto.Context, sampleRate int) {
+	m.mu.Lock()
+	m.sampleRate = sampleRate
+	m.mu.Unlock()
+
+	player := ctx.NewPlayer(m)
+	player.SetBufferSize(int(secondsToBytes(masterBufferDuration.Seconds(), sampleRate)))
+	player.Play()
+
+	m.mu.Lock()
+	m.player = player
+	m.mu.Unlock()
+}
+
+// rate возвращает частоту дискретизации шины (0, если движок ещё не запущен).
+func (m *mixer) rate() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sampleRate
+}
+
+// add подключает голос к шине.
+func (m *mixer) add(v *voice) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.voices = append(m.voices, v)
+}
+
+// remove отключает голос от шины.
+func (m *mixer) remove(v *voice) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, cur := range m.voices {
+		if cur == v {
+			m.voices = append(m.voices[:i], m.voices[i+1:]...)
+			return
+		}
+	}
+}
+
+// Read реализует io.Reader для плеера oto. Микшер никогда не возвращает EOF:
+// если играть нечего, он отдаёт тишину.
+func (m *mixer) Read(p []byte) (int, error) {
+	frames := len(p) / frameBytes
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cap(m.mix) < frames*2 {
+		m.mix = make([]float32, frames*2)
+	}
+	buf := m.mix[:frames*2]
+	clear(buf)
+
+	for _, v := range m.voices {
+		v.mixInto(buf, m.frames)
+	}
+	m.frames += int64(frames)
+	m.master.process(buf, m.sampleRate)
+	m.meter.write(buf, m.sampleRate)
+
+	floatsToBytes(buf, p)
+	return frames * frameBytes, nil
+}
+
+// bufferedDuration возвращает длительность данных, уже смешанных, но ещё не отправленных на устройство.
+func (m *mixer) bufferedDuration() time.Duration {
+	m.mu.Lock()
+	player, rate := m.player, m.sampleRate
+	m.mu.Unlock()
+
+	if player == nil {
+		return 0
+	}
+	seconds := bytesToSeconds(int64(player.BufferedSize()), rate)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// frameAt возвращает кадр таймлайна, который прозвучит в момент t, с учётом
+// уже смешанных, но не отправленных данных и задержки устройства.
+// Кадры, которые уже смешаны, заменяются ближайшим ещё не смешанным.
+func (m *mixer) frameAt(t time.Time) int64 {
+	// Смешанный сейчас кадр прозвучит после буфера плеера и задержки устройства
+	buffered := m.bufferedDuration()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ahead := time.Until(t) - buffered - OutputLatency()
+	frame := m.frames + int64(ahead.Seconds()*float64(m.sampleRate))
+	return max(frame, m.frames)
+}
+
+// startFrame возвращает кадр таймлайна, с которого голос начал (или начнёт) звучать.
+// Голос, ещё не попавший в шину, зазвучит со следующей порции микшера.
+func (m *mixer) startFrame(v *voice) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch {
+	case v.started >= 0:
+		return v.started
+	case v.startAt > m.frames:
+		return v.startAt
+	}
+	return m.frames
+}
+
+// sync выполняет fn между порциями микшера: пока она работает, шина не
+// смешивается, поэтому изменения нескольких голосов вступают в силу на одном кадре.
+func (m *mixer) sync(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn()
+}
+
+// startTogether назначает голосам общий кадр начала — следующую порцию шины.
+func (m *mixer) startTogether(voices []*voice) {
+	m.sync(func() {
+		for _, v := range voices {
+			v.mu.Lock()
+			v.startAt = m.frames
+			v.mu.Unlock()
+		}
+	})
+}
+
+// voice — один звук на шине микшера. Повторяет ту часть API oto.Player,
+// которой пользуются функции управления: пауза, громкость, перемотка.
+type voice struct {
+	mu       sync.Mutex
+	source   *trackingStream
+	volume   float64
+	gain     float64 // Усиление нормализации громкости, применяется вместе с volume
+	gainTo   float64 // К какому усилению плавно идёт gain (см. rampGain)
+	gainStep float64 // Изменение gain за кадр; 0 — усиление не меняется
+	trim     float64 // Громкость дорожки TrackGroup, применяется вместе с volume и gain
+	playing  bool
+	buf      []float32
+	loop     bool   // Перематывать поток в начало, дойдя до конца (PlayParams.Loop)
+	onEnd    func() // Вызывается в потоке микшера, когда поток закончился; не должна блокироваться
+	onStart  func() // Вызывается один раз, когда голос зазвучал или был снят с паузы раньше; не должна блокироваться
+	startAt  int64  // Кадр таймлайна, раньше которого голос молчит (0 — сразу)
+	started  int64  // Кадр таймлайна, с которого голос зазвучал; -1 — ещё не звучал
+	resumed  int64  // Сколько кадров голос смешал после последнего запуска или снятия с паузы
+	ended    int64  // Кадр таймлайна, на котором закончился поток (пока играет — 0)
+	declick  seekFade
+}
+
+// seekFade — огибающая перемотки без щелчка: голос затихает, перематывается
+// в потоке микшера и снова нарастает.
+type seekFade struct {
+	pending bool    // Перемотка ждёт, пока голос затихнет
+	target  int64   // Куда перемотать (байты исходного трека)
+	drop    float32 // Насколько огибающая приглушает голос: 0 — не приглушает, 1 — тишина
+	step    float32 // Изменение drop за кадр
+}
+
+// framesLeft возвращает, сколько кадров осталось до полной тишины.
+func (f *seekFade) framesLeft() int {
+	return int(math.Ceil(float64((1 - f.drop) / f.step)))
+}
+
+// apply приглушает кадры buf по огибающей и продвигает её.
+func (f *seekFade) apply(buf []float32) {
+	if !f.pending && f.drop <= 0 {
+		return
+	}
+	for i := 0; i+1 < len(buf); i += 2 {
+		if f.pending {
+			f.drop = min(f.drop+f.step, 1)
+		} else {
+			f.drop = max(f.drop-f.step, 0)
+		}
+		buf[i] *= 1 - f.drop
+		buf[i+1] *= 1 - f.drop
+	}
+}
+
+// newVoice создаёт голос для потока. Новый голос стоит на паузе с громкостью 1.
+func newVoice(source *trackingStream) *voice {
+	return &voice{source: source, volume: 1, gain: 1, trim: 1, started: -1}
+}
+
+// mixInto добавляет очередную порцию кадров голоса в шину с учётом громкости.
+// Когда поток заканчивается, голос перестаёт играть, как плеер oto после EOF.
+// pos — кадр таймлайна, с которого начинается dst: запланированный голос
+// вступает ровно с кадра startAt, даже если он приходится на середину порции.
+func (v *voice) mixInto(dst []float32, pos int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.playing {
+		return
+	}
+	if v.startAt > pos {
+		skip := v.startAt - pos
+		if skip >= int64(len(dst)/2) {
+			return
+		}
+		dst = dst[skip*2:]
+		pos = v.startAt
+	}
+	if v.started < 0 {
+		v.started = pos
+		v.fireStart()
+	}
+
+	if cap(v.buf) < len(dst) {
+		v.buf = make([]float32, len(dst))
+	}
+	buf := v.buf[:len(dst)]
+
+	got := 0
+	rewoundAt := -1 // Сколько кадров было набрано к последней перемотке Loop
+	for got < len(dst)/2 {
+		// Пока голос затихает перед перемоткой, читаем ровно до тишины
+		end := len(dst) / 2
+		if v.declick.pending {
+			end = min(end, got+v.declick.framesLeft())
+		}
+		n, err := v.source.readProcessed(buf[got*2 : end*2])
+		v.declick.apply(buf[got*2 : (got+n)*2])
+		got += n
+		if v.declick.pending && (v.declick.drop >= 1 || err != nil || n == 0) {
+			// Если перемотать не удалось, голос нарастает с прежнего места
+			v.declick.pending = false
+			v.source.Seek(v.declick.target, io.SeekStart)
+			continue
+		}
+		if err != nil || n == 0 {
+			if err != io.EOF && n != 0 {
+				break
+			}
+			// Зацикленный звук перематывается прямо здесь, без паузы между кругами.
+			// Если после перемотки не прочитано ни кадра, поток пуст — останавливаемся.
+			if v.loop && got != rewoundAt && v.source.Rewind() == nil {
+				rewoundAt = got
+				continue
+			}
+			v.playing = false
+			v.ended = pos + int64(got)
+			if v.onEnd != nil {
+				v.onEnd()
+			}
+			break
+		}
+	}
+
+	v.resumed += int64(got)
+	if v.gainStep == 0 {
+		vol := float32(v.volume * v.gain * v.trim)
+		for i, s := range buf[:got*2] {
+			dst[i] += s * vol
+		}
+		return
+	}
+	// Усиление нормализации меняется плавно, покадрово
+	for i := 0; i < got; i++ {
+		v.gain += v.gainStep
+		if (v.gainStep > 0) == (v.gain >= v.gainTo) {
+			v.gain, v.gainStep = v.gainTo, 0
+		}
+		vol := float32(v.volume * v.gain * v.trim)
+		dst[i*2] += buf[i*2] * vol
+		dst[i*2+1] += buf[i*2+1] * vol
+	}
+}
+
+// Play запускает или возобновляет голос.
+func (v *voice) Play() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.playing {
+		v.resumed = 0
+	}
+	v.playing = true
+}
+
+// Pause приостанавливает голос. Перемотка, ждавшая затухания, выполняется сразу.
+func (v *voice) Pause() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.playing = false
+	v.fireStart()
+	if v.declick.pending {
+		v.source.Seek(v.declick.target, io.SeekStart)
+		v.declick = seekFade{}
+	}
+}
+
+// watchEnd задаёт функцию, вызываемую по окончании потока.
+// Возвращает false, если голос уже не играет и fn не будет вызвана.
+func (v *voice) watchEnd(fn func()) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onEnd = fn
+	return v.playing
+}
+
+// whenStarted вызывает fn, когда запущенный, но ещё не звучавший голос
+// зазвучит (запланированный — на своём кадре startAt). Если голос уже звучал
+// или не запущен, возвращает false, и fn не вызывается. Если голос поставят
+// на паузу раньше, fn вызывается при паузе, чтобы ожидающие не зависли.
+func (v *voice) whenStarted(fn func()) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.playing || v.started >= 0 {
+		return false
+	}
+	v.onStart = fn
+	return true
+}
+
+// fireStart вызывает и сбрасывает onStart. Вызывается под v.mu.
+func (v *voice) fireStart() {
+	if v.onStart != nil {
+		v.onStart()
+		v.onStart = nil
+	}
+}
+
+// endFrame возвращает кадр таймлайна, на котором закончился поток голоса.
+func (v *voice) endFrame() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.ended
+}
+
+// IsPlaying сообщает, играет ли голос: false на паузе и после конца потока.
+func (v *voice) IsPlaying() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.playing
+}
+
+// Volume возвращает текущую громкость голоса.
+func (v *voice) Volume() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.volume
+}
+
+// SetVolume задаёт громкость голоса (0..1).
+func (v *voice) SetVolume(volume float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.volume = volume
+}
+
+// rampGain за frames кадров плавно меняет усиление нормализации до gain.
+func (v *voice) rampGain(gain float64, frames int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if frames <= 0 || gain == v.gain {
+		v.gain, v.gainStep = gain, 0
+		return
+	}
+	v.gainTo, v.gainStep = gain, (gain-v.gain)/float64(frames)
+}
+
+// setTrim задаёт громкость дорожки, применяемую вместе с громкостью и нормализацией.
+func (v *voice) setTrim(trim float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.trim = trim
+}
+
+// Seek перематывает поток голоса. Перемотка, ждавшая затухания, отменяется.
+func (v *voice) Seek(offset int64, whence int) (int64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.declick.pending = false
+	return v.source.Seek(offset, whence)
+}
+
+// seekSmooth перематывает поток голоса к offset от начала трека. С fadeFrames > 0
+// играющий голос сначала затихает за fadeFrames кадров, перематывается прямо
+// в потоке микшера и за столько же кадров нарастает обратно. Повторная
+// перемотка во время затухания только меняет цель.
+func (v *voice) seekSmooth(offset int64, fadeFrames int) (int64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if fadeFrames <= 0 || !v.playing || v.started < 0 {
+		v.declick.pending = false
+		return v.source.Seek(offset, io.SeekStart)
+	}
+	v.declick.pending = true
+	v.declick.target = offset / frameBytes * frameBytes
+	v.declick.step = 1 / float32(fadeFrames)
+	return v.declick.target, nil
+}
+
+// pendingSeek возвращает цель перемотки, ждущей затухания голоса.
+func (v *voice) pendingSeek() (int64, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.declick.target, v.declick.pending
+}
+
+// sinceResume возвращает длительность звука, смешанного голосом после
+// последнего запуска или снятия с паузы.
+func (v *voice) sinceResume() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	seconds := float64(v.resumed) / float64(v.source.OutputRate())
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// bufferedDuration возвращает, сколько уже смешанного звука ещё не прозвучало.
+func (v *voice) bufferedDuration() time.Duration {
+	return masterMixer.bufferedDuration()
+}
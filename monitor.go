@@ -6,104 +6,127 @@ import (
 	"io"
 	"sync"
 	"time"
+)
 
-	"github.com/ebitengine/oto/v3"
+const (
+	defaultFadeOutTime = time.Second             // Длительность затухания по умолчанию
+	defaultFadeInTime  = 1500 * time.Millisecond // Длительность нарастания по умолчанию
+	fadeInterval       = 20 * time.Millisecond   // Пауза между шагами плавного изменения громкости
 )
 
-// monitorPlayback следит за окончанием трека и реализует логику Loop.
-func monitorPlayback(ctx context.Context, closer io.Closer, stream decodedStream, player *oto.Player, done chan struct{}, params PlayParams) {
-	var closeOnce sync.Once
-	safeClose := func() {
-		closeOnce.Do(func() { close(done) })
-	}
+// monitorPlayback отслеживает окончание трека и его остановку.
+// Отдельной горутины на звук нет: конец потока сообщает сам голос из потока
+// микшера, а остановку по Stop/StopAll — context.AfterFunc. Loop обрабатывается
+// голосом при чтении. Планировщик только отсчитывает время до конца звука, а
+// ресурсы освобождаются в отдельной горутине: закрытие временного файла
+// URL-звука ждёт, и шаги плавности остальных звуков не должны стоять за ним.
+func monitorPlayback(ctx context.Context, closer io.Closer, stream decodedStream, done chan struct{}, sc *soundController) {
+	player := sc.player
+	var once sync.Once
+	var stopWatch func() bool
 
-	go func() {
-		// Гарантируем закрытие файлов и каналов при выходе из функции.
-		defer func() {
+	// finish освобождает ресурсы звука и закрывает done.
+	finish := func() {
+		once.Do(func() {
+			stopWatch()
+			sc.stopped()
 			activeMu.Lock()
 			delete(activeSounds, done)
 			activeMu.Unlock()
+			masterMixer.remove(player)
+			if ts, ok := stream.(*trackingStream); ok {
+				ts.cues.close()
+				ts.meter.close()
+			}
 			closer.Close()
-			safeClose()
-		}()
+			close(done)
+		})
+	}
 
-		currentPlayer := player
+	// Остановка по сигналу Stop или StopAll. AfterFunc запускает функцию в
+	// собственной горутине, поэтому здесь можно дождаться затухания.
+	// Звук на паузе останавливается сразу, а затухающий перед паузой
+	// дозатухает с той громкости, до которой успел дойти.
+	stopWatch = context.AfterFunc(ctx, func() {
+		sc.mu.Lock()
+		fade := sc.stopFade
+		sc.mu.Unlock()
+		if fade > 0 && sc.State() != StatePaused {
+			if gen, err := sc.switchTo(StateFading, StateStopped, nil); err == nil {
+				faded := make(chan struct{})
+				sc.fade(gen, fade, nil, func(bool) { close(faded) })
+				<-faded
+			}
+		}
+		player.Pause()
+		finish()
+	})
 
-		for {
-			activeMu.Lock()
-			currentSound, exists := activeSounds[done]
-			activeMu.Unlock()
+	// Конец потока: ждём, пока последние кадры, уже смешанные в буфер
+	// устройства, прозвучат, и только тогда закрываем done.
+	ended := func() {
+		sched.after(0, func() {
+			sched.after(masterMixer.bufferedDuration(), func() { go finish() })
+		})
+	}
+	if !player.watchEnd(ended) {
+		ended()
+	}
+}
 
-			if !exists {
-				return
-			}
-			// Если музыка перестала играть (дошла до конца).
-			if !currentPlayer.IsPlaying() && !currentSound.isPaused {
-				if params.Loop {
-					// Перематываем поток в начало.
-					_, err := stream.Seek(0, io.SeekStart)
-					if err != nil {
-						return
-					}
-					// // Создаем новый плеер для "чистого" перезапуска.
-					// currentPlayer = otoCtx.NewPlayer(stream)
-					// currentPlayer.SetVolume(params.Volume)
-					currentPlayer.Play()
-					// Защита от слишком частого перезапуска.
-					time.Sleep(200 * time.Millisecond)
-				} else {
-					return
-				}
-			}
-			select {
-			case <-ctx.Done(): // Остановка по сигналу StopAll.
-				if params.FadeOut {
-					fadeOut(currentPlayer)
-				}
-				currentPlayer.Pause()
+// fade плавно меняет громкость звука в рамках перехода поколения gen: от
+// текущей до нуля или, если звук нарастает к StatePlaying, до громкости
+// пользователя. Цель перечитывается на каждом шаге, поэтому SetVolume во
+// время нарастания меняет конечную громкость, а не спорит с ним. Шаг и
+// проверка поколения выполняются под блокировкой звука: прерванная плавность
+// громкость больше не трогает, а следующий переход продолжает с той громкости,
+// на которой она остановилась.
+//
+// По окончании звук переходит в целевое состояние через endFade(gen, apply).
+// then (nil — не нужно) получает true, если плавность дошла до конца.
+// Шаги выполняет планировщик, поэтому fade не блокирует вызывающего.
+func (sc *soundController) fade(gen uint64, d time.Duration, apply func(), then func(ok bool)) {
+	var start time.Time
+	var from float64
+	var step func()
+	step = func() {
+		sc.mu.Lock()
+		if sc.state != StateFading || sc.fadeGen != gen {
+			sc.mu.Unlock()
+			finishFade(then, false)
+			return
+		}
+		if start.IsZero() {
+			// Нарастание запланированного звука отсчитывается с момента, когда
+			// микшер дойдёт до его первого кадра, а не с вызова
+			if sc.fadeTo == StatePlaying && sc.player.whenStarted(func() { sched.after(0, step) }) {
+				sc.mu.Unlock()
 				return
-			case <-time.After(100 * time.Millisecond):
 			}
+			start, from = time.Now(), sc.player.Volume()
 		}
-	}()
-}
+		target := 0.0
+		if sc.fadeTo == StatePlaying {
+			target = sc.volume
+		}
+		progress := 1.0
+		if d > 0 {
+			progress = min(time.Since(start).Seconds()/d.Seconds(), 1)
+		}
+		sc.player.SetVolume(from + (target-from)*progress)
+		sc.mu.Unlock()
 
-// fadeIn постепенно поднимает громкость плеера до целевого значения
-func fadeIn(player *oto.Player, targetVolume float64) {
-	step := 0.02
-	for v := 0.0; v <= targetVolume; v += step {
-		if player.Volume() > v+step {
+		if progress < 1 {
+			sched.after(fadeInterval, step)
 			return
 		}
-		player.SetVolume(v)
-		time.Sleep(30 * time.Millisecond)
+		finishFade(then, sc.endFade(gen, apply))
 	}
-	player.SetVolume(targetVolume)
+	sched.after(0, step)
 }
 
-// fadeOut постепенно снижает громкость плеера до нуля
-func fadeOut(player *oto.Player) {
-	currentVol := player.Volume()
-	if currentVol <= 0 {
-		return
+func finishFade(then func(ok bool), ok bool) {
+	if then != nil {
+		then(ok)
 	}
-
-	// Рассчитываем шаг так, чтобы всегда было 20 итераций.
-	// Если громкость 0.1, шаг будет 0.005. Если 1.0, шаг будет 0.05.
-	step := currentVol / 20.0
-
-	for range 20 {
-		currentVol -= step
-		if currentVol < 0 {
-			currentVol = 0
-		}
-		player.SetVolume(currentVol)
-		time.Sleep(50 * time.Millisecond)
-
-		if currentVol <= 0 {
-			break
-		}
-	}
-	// На всякий случай фиксируем чистый ноль в конце
-	player.SetVolume(0)
-}
\ No newline at end of file
+}
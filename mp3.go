@@ -0,0 +1,225 @@
+package playsound
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// mp3DecoderDelay — задержка синтезирующего фильтра декодера MP3 в семплах:
+// на столько звук на выходе декодера отстаёт от звука на входе кодера.
+const mp3DecoderDelay = 529
+
+// mp3Info — сведения из служебного кадра Xing/Info или VBRI и тега LAME.
+type mp3Info struct {
+	frames          int64 // Число звуковых кадров без служебного; 0 — неизвестно
+	samplesPerFrame int64
+	delay           int64 // Задержка кодера в семплах (тег LAME)
+	padding         int64 // Тишина, дописанная кодером в конец, в семплах (тег LAME)
+	gapless         bool  // Задержка и дополнение известны, их можно вырезать
+}
+
+// trim возвращает, сколько байт декодированного звука пропустить в начале
+// и какова точная длина звука в байтах. Служебный кадр декодер выдаёт как
+// кадр тишины, поэтому он пропускается всегда; задержка кодера и декодера
+// и дополнение — если их сообщил тег LAME. decoded — сколько байт выдаёт
+// декодер (-1 — неизвестно): длина не может выйти за него.
+func (info *mp3Info) trim(decoded int64) (skip, length int64) {
+	skip, length = info.samplesPerFrame, -1
+	if info.frames > 0 {
+		length = info.frames * info.samplesPerFrame
+	}
+	if info.gapless {
+		skip += info.delay + mp3DecoderDelay
+		if length > 0 {
+			length = max(length-info.delay-info.padding, 0)
+		}
+	}
+	skip *= frameBytes
+	if length > 0 {
+		length *= frameBytes
+	}
+
+	if decoded >= 0 {
+		available := max(decoded-skip, 0)
+		if length < 0 || length > available {
+			length = available
+		}
+	}
+	return skip, length
+}
+
+// readMP3Info ищет первый кадр после тегов и читает из него заголовки
+// Xing/Info или VBRI. Возвращает nil, если служебного кадра нет (обычно это
+// CBR-файл без тега LAME). Поток возвращается в начало.
+func readMP3Info(rs io.ReadSeeker) *mp3Info {
+	defer rs.Seek(0, io.SeekStart)
+
+	var offset int64
+	if tag, _ := readID3v2(rs); tag != nil {
+		offset = tag.size
+	}
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return nil
+	}
+	buf := make([]byte, mp3SyncSearchLimit)
+	n, _ := io.ReadFull(rs, buf)
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if h, ok := parseMP3Header(buf[i : i+4]); ok && h.layer == 3 {
+			return parseMP3InfoFrame(buf[i:], h)
+		}
+	}
+	return nil
+}
+
+// parseMP3InfoFrame разбирает служебный заголовок в первом кадре frame Layer III.
+func parseMP3InfoFrame(frame []byte, h mp3Header) *mp3Info {
+	info := &mp3Info{samplesPerFrame: 1152}
+	sideInfo := 32 // Размер побочной информации кадра, байт
+	switch {
+	case h.version == 1 && h.channels == 1:
+		sideInfo = 17
+	case h.version != 1:
+		info.samplesPerFrame = 576
+		sideInfo = 17
+		if h.channels == 1 {
+			sideInfo = 9
+		}
+	}
+
+	// Xing (VBR) или Info (CBR) — сразу после побочной информации кадра
+	if p := 4 + sideInfo; len(frame) >= p+8 && (string(frame[p:p+4]) == "Xing" || string(frame[p:p+4]) == "Info") {
+		flags := binary.BigEndian.Uint32(frame[p+4:])
+		p += 8
+		if flags&1 != 0 && len(frame) >= p+4 {
+			info.frames = int64(binary.BigEndian.Uint32(frame[p:]))
+			p += 4
+		}
+		if flags&2 != 0 {
+			p += 4 // Размер файла в байтах
+		}
+		if flags&4 != 0 {
+			// Оглавление для перемотки не нужно: декодер при открытии строит
+			// точный индекс кадров
+			p += 100
+		}
+		if flags&8 != 0 {
+			p += 4 // Качество VBR
+		}
+		parseLAMETag(frame[min(p, len(frame)):], info)
+		return info
+	}
+
+	// VBRI (кодер Fraunhofer) — всегда через 32 байта после заголовка
+	if p := 4 + 32; len(frame) >= p+18 && string(frame[p:p+4]) == "VBRI" {
+		info.frames = int64(binary.BigEndian.Uint32(frame[p+14:]))
+		return info
+	}
+	return nil
+}
+
+// parseLAMETag читает задержку и дополнение кодера из тега LAME, который
+// идёт сразу за полями Xing. Такой же тег пишут кодеры на основе FFmpeg.
+func parseLAMETag(b []byte, info *mp3Info) {
+	if len(b) < 24 {
+		return
+	}
+	switch string(b[:4]) {
+	case "LAME", "Lavf", "Lavc":
+	default:
+		return
+	}
+	d := b[21:24]
+	info.delay = int64(d[0])<<4 | int64(d[1])>>4
+	info.padding = int64(d[1]&0x0f)<<8 | int64(d[2])
+	info.gapless = true
+}
+
+// mp3Stream декодирует MP3 без служебного кадра, задержки кодера и дополнения
+// в конце: длина потока совпадает с длиной исходного звука до семпла, а
+// зацикленный трек звучит без щелчка на стыке. Позиции отсчитываются от
+// первого настоящего семпла. Перемотка идёт по индексу кадров, который
+// go-mp3 строит при открытии файла, поэтому точна и для VBR.
+type mp3Stream struct {
+	*mp3.Decoder
+	skip   int64 // Сколько байт декодированного звука пропускается в начале
+	length int64 // Длина звука в байтах; -1 — неизвестна
+	pos    int64 // Позиция относительно первого настоящего семпла
+}
+
+// newMP3Stream открывает MP3 с учётом заголовков Xing/Info, VBRI и LAME.
+func newMP3Stream(rs io.ReadSeeker) (*mp3Stream, error) {
+	info := readMP3Info(rs)
+
+	d, err := mp3.NewDecoder(rs)
+	if err != nil {
+		return nil, err
+	}
+	s := &mp3Stream{Decoder: d, length: d.Length()}
+	if info != nil {
+		s.skip, s.length = info.trim(d.Length())
+	}
+	if s.skip > 0 && d.Length() >= 0 {
+		if _, err := d.Seek(s.skip, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Read читает декодированный звук, обрезая дополнение кодера в конце.
+func (s *mp3Stream) Read(p []byte) (int, error) {
+	if s.length >= 0 {
+		if s.pos >= s.length {
+			return 0, io.EOF
+		}
+		p = p[:min(int64(len(p)), s.length-s.pos)]
+	}
+	n, err := s.Decoder.Read(p)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Seek перематывает поток; позиция ограничивается концом звука.
+func (s *mp3Stream) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = s.pos + offset
+	case io.SeekEnd:
+		if s.length < 0 {
+			return 0, fmt.Errorf("stream length is unknown")
+		}
+		pos = s.length + offset
+	default:
+		return 0, fmt.Errorf("invalid whence")
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("negative position %d", pos)
+	}
+	if s.Decoder.Length() < 0 {
+		return 0, fmt.Errorf("mp3 stream is not seekable")
+	}
+
+	// Конец звука: декодер не трогаем, следующее чтение вернёт io.EOF
+	if s.length >= 0 && pos >= s.length {
+		s.pos = s.length
+		return s.pos, nil
+	}
+	if _, err := s.Decoder.Seek(s.skip+pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.pos = pos
+	return pos, nil
+}
+
+// Length возвращает длину звука в байтах без задержки и дополнения кодера.
+func (s *mp3Stream) Length() int64 {
+	return s.length
+}
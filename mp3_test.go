@@ -0,0 +1,119 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// mp3Frame — тихий кадр MPEG-1 Layer III, 128 кбит/с, 44100 Гц, стерео (417 байт).
+func mp3Frame() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	return frame
+}
+
+// testMP3 собирает файл из frames тихих кадров. Если info не пуст, первым
+// идёт служебный кадр с этим заголовком сразу после побочной информации.
+func testMP3(frames int, info []byte) []byte {
+	var b bytes.Buffer
+	if info != nil {
+		frame := mp3Frame()
+		copy(frame[4+32:], info)
+		b.Write(frame)
+	}
+	for i := 0; i < frames; i++ {
+		b.Write(mp3Frame())
+	}
+	return b.Bytes()
+}
+
+// infoHeader — заголовок Info с числом кадров и тегом LAME.
+func infoHeader(frames uint32, delay, padding int) []byte {
+	b := []byte("Info")
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint32(b, frames)
+	lame := make([]byte, 24)
+	copy(lame, "LAME3.100")
+	lame[21] = byte(delay >> 4)
+	lame[22] = byte(delay<<4) | byte(padding>>8)
+	lame[23] = byte(padding)
+	return append(b, lame...)
+}
+
+// Тест тега LAME: из звука вырезаются служебный кадр, задержка и дополнение
+func TestMP3Gapless(t *testing.T) {
+	s, err := newMP3Stream(bytes.NewReader(testMP3(10, infoHeader(10, 576, 1000))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := int64(10*1152-576-1000) * frameBytes
+	if s.Length() != want {
+		t.Fatalf("Length() = %d; want %d", s.Length(), want)
+	}
+
+	data, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != want {
+		t.Errorf("read %d bytes; want %d", len(data), want)
+	}
+
+	if pos, err := s.Seek(1000*frameBytes, io.SeekStart); err != nil || pos != 1000*frameBytes {
+		t.Fatalf("Seek() = %d, %v", pos, err)
+	}
+	if data, _ := io.ReadAll(s); int64(len(data)) != want-1000*frameBytes {
+		t.Errorf("read %d bytes after seek; want %d", len(data), want-1000*frameBytes)
+	}
+	if pos, err := s.Seek(0, io.SeekEnd); err != nil || pos != want {
+		t.Errorf("Seek(end) = %d, %v; want %d", pos, err, want)
+	}
+	if n, err := s.Read(make([]byte, 16)); n != 0 || err != io.EOF {
+		t.Errorf("Read() at end = %d, %v; want io.EOF", n, err)
+	}
+}
+
+// Тест длительности: без тега LAME пропускается только служебный кадр,
+// а файл без служебного кадра декодируется целиком
+func TestMP3Length(t *testing.T) {
+	xing := []byte("Xing")
+	xing = binary.BigEndian.AppendUint32(xing, 1)
+	xing = binary.BigEndian.AppendUint32(xing, 10)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int64
+	}{
+		{"Xing", testMP3(10, xing), 10 * 1152 * frameBytes},
+		{"CBR", testMP3(10, nil), 10 * 1152 * frameBytes},
+		{"Xing frames beyond file", testMP3(5, xing), 5 * 1152 * frameBytes},
+	}
+	for _, tt := range tests {
+		s, err := newMP3Stream(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if s.Length() != tt.want {
+			t.Errorf("%s: Length() = %d; want %d", tt.name, s.Length(), tt.want)
+		}
+	}
+}
+
+func TestParseVBRI(t *testing.T) {
+	frame := mp3Frame()
+	vbri := frame[4+32:]
+	copy(vbri, "VBRI")
+	binary.BigEndian.PutUint32(vbri[14:], 1234)
+
+	h, _ := parseMP3Header(frame)
+	info := parseMP3InfoFrame(frame, h)
+	if info == nil || info.frames != 1234 || info.gapless {
+		t.Errorf("parseMP3InfoFrame() = %+v; want 1234 frames without gapless info", info)
+	}
+	if parseMP3InfoFrame(mp3Frame(), h) != nil {
+		t.Error("plain frame parsed as info frame")
+	}
+}
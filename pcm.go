@@ -0,0 +1,33 @@
+package playsound
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// frameBytes — размер одного стерео-кадра: 2 канала * 2 байта на семпл (int16).
+const frameBytes = 4
+
+// frameReader заполняет dst стерео-кадрами в формате float32 (L и R чередуются)
+// и возвращает количество записанных кадров.
+type frameReader func(dst []float32) (int, error)
+
+// bytesToFloats переводит семплы int16 LE в float32 в диапазоне [-1, 1].
+func bytesToFloats(src []byte, dst []float32) {
+	for i := range dst {
+		dst[i] = float32(int16(binary.LittleEndian.Uint16(src[i*2:]))) / 32768
+	}
+}
+
+// floatsToBytes переводит float32 обратно в int16 LE, обрезая значения за пределами [-1, 1].
+func floatsToBytes(src []float32, dst []byte) {
+	for i, v := range src {
+		s := math.Round(float64(v) * 32768)
+		if s > math.MaxInt16 {
+			s = math.MaxInt16
+		} else if s < math.MinInt16 {
+			s = math.MinInt16
+		}
+		binary.LittleEndian.PutUint16(dst[i*2:], uint16(int16(s)))
+	}
+}
@@ -3,16 +3,31 @@ package playsound
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 )
 
 // PlayParams содержит настройки воспроизведения.
 type PlayParams struct {
-	Volume   float64 // Громкость NB! Тишина это -1, не 0!
-	Loop     bool    // Зацикливание трека
-	FadeOut  bool    // Постепенное затухание звука
-	FadeIn   bool    // Постепенное увеличение громкости
-	Position float64     // С какой секунды начать
+	Volume        float64        // Громкость NB! Тишина это -1, не 0!
+	Loop          bool           // Зацикливание трека
+	FadeOut       bool           // Постепенное затухание звука
+	FadeIn        bool           // Постепенное увеличение громкости
+	FadeOutTime   time.Duration  // Длительность затухания (0 — 1 с)
+	FadeInTime    time.Duration  // Длительность нарастания (0 — 1,5 с)
+	Position      float64        // С какой секунды начать
+	Speed         float64        // Скорость воспроизведения (0 — обычная, 0.5 — вдвое медленнее)
+	Pitch         float64        // Сдвиг высоты тона в полутонах
+	PreservePitch bool           // Сохранять высоту тона при изменении скорости
+	Reverse       bool           // Воспроизведение задом наперёд (с конца или с Position к началу)
+	Effects       []Processor    // Цепочка эффектов (фильтры, эквалайзер, реверберация, эхо), применяемых по порядку
+	Normalize     bool           // Выравнивать громкость трека до TargetLUFS (по ReplayGain или измерению)
+	TargetLUFS    float64        // Целевая громкость нормализации (0 — -18 LUFS)
+	Pan           float64        // Стереопанорама: -1 — слева, 0 — по центру, 1 — справа
+	Spatial       *SpatialParams // Положение источника в 3D-пространстве (nil — обычный стереозвук)
+	Group         string         // Произвольная группа звука (например, "music" или "ui") для фильтрации и лимитов голосов
+	Priority      int            // Приоритет при вытеснении голосов (StealLowestPriority): чем больше, тем важнее
 }
 
 // PlaySound — упрощенная функция для разового проигрывания на полной громкости.
@@ -28,18 +43,30 @@ func PlaySound(filePath string) (chan struct{}, error) {
 func PlaySoundWithParams(filePath string, params PlayParams) (chan struct{}, error) {
 	params = validateParams(params)
 
-	// Шаг 1: Получаем доступ к данным (файл или сеть).
-	rs, closer, err := getReadSeeker(filePath)
-	if err != nil {
-		return nil, err
+	// Лимит источника проверяем до открытия файла: пропущенный или
+	// перезапущенный звук не тратит время на декодирование.
+	if done, err := retrigger(filePath); done != nil || err != nil {
+		return done, err
 	}
+	return startSound(filePath, params, nil)
+}
 
-	// Шаг 2: Инициализируем нужный декодер.
-	stream, err := getDecoder(rs, filePath)
+// startSound открывает источник, регистрирует звук и подключает его к шине.
+// startAt, если задана, возвращает кадр таймлайна микшера, с которого звук
+// должен вступить; она вызывается после запуска движка.
+func startSound(filePath string, params PlayParams, startAt func() int64) (chan struct{}, error) {
+	// Запоминаем, сколько раз вызывался StopAll: звук, запуск которого
+	// начался до очередного StopAll, не должен пережить его.
+	activeMu.Lock()
+	epoch := stopEpoch
+	activeMu.Unlock()
+
+	// Шаги 1-2: Открываем источник, читаем теги и выбираем декодер.
+	snd, err := openSound(filePath, params)
 	if err != nil {
-		closer.Close()
 		return nil, err
 	}
+	stream, closer := snd.stream, snd.closer
 
 	// Шаг 3: Подготавливаем аудио-движок.
 	if err := initEngine(stream.SampleRate()); err != nil {
@@ -47,56 +74,162 @@ func PlaySoundWithParams(filePath string, params PlayParams) (chan struct{}, err
 		return nil, err
 	}
 
-	// Шаг 4: Создаем и запускаем плеер.
-	tracker := &trackingStream{decodedStream: stream}
-	player := otoCtx.NewPlayer(tracker)
-
-	// Если включен FadeIn, начинаем с нуля, иначе ставим целевую громкость сразу
-	startVol := params.Volume
-	if params.FadeIn {
-		startVol = 0
+	// Шаг 4: Создаем голос на шине микшера и запускаем его.
+	tracker, player, err := newSoundVoice(stream, params, snd.normGain, masterMixer.rate())
+	if err != nil {
+		closer.Close()
+		return nil, err
 	}
-	player.SetVolume(startVol)
 
-	// Если указана стартовая позиция — перематываем плеер
-	if params.Position > 0 {
-		offset := secondsToBytes(params.Position, stream.SampleRate())
-		_, err = player.Seek(offset, io.SeekStart)
-		if err != nil {
-			return nil, err
-		}
+	if startAt != nil {
+		player.startAt = startAt()
 	}
 
-	var tBytes int64
-    if l, ok := stream.(interface{ Length() int64 }); ok {
-        tBytes = l.Length()
-    } else if l, ok := stream.(interface{ Length() int }); ok {
-        tBytes = int64(l.Length())
-    }
+	tBytes := streamLength(stream)
 
-	mu.Lock()
-	soundCtx, soundCancel := context.WithCancel(rootCtx)
-	mu.Unlock()
+	soundCtx, soundCancel := context.WithCancel(context.Background())
 
 	done := make(chan struct{})
 	activeMu.Lock()
-	activeSounds[done] = soundController{
+	// Проверяем лимиты голосов под той же блокировкой, что и регистрацию,
+	// чтобы параллельные запуски не превысили лимит.
+	victims, restarted, err := admitVoice(filePath, params)
+	if err == nil && restarted == nil && stopEpoch != epoch {
+		err = fmt.Errorf("stopped by StopAll while starting")
+	}
+	if err != nil || restarted != nil {
+		// Параллельный запуск того же источника успел раньше: вместо нового
+		// звука перезапущен его экземпляр
+		activeMu.Unlock()
+		soundCancel()
+		tracker.cues.close()
+		tracker.meter.close()
+		closer.Close()
+		return restarted, err
+	}
+	stealVoices(victims)
+	control := &soundController{
 		cancel:     soundCancel,
 		player:     player,
 		params:     params,
 		sampleRate: stream.SampleRate(),
 		tracker:    tracker,
+		volume:     params.Volume,
 		totalBytes: tBytes,
+		tags:       snd.tags,
+		cover:      snd.cover,
+		source:     filePath,
+		seq:        soundSeq.Add(1),
+		started:    time.Now(),
 	}
+	activeSounds[done] = control
 	activeMu.Unlock()
 
-	player.Play()
+	masterMixer.add(player)
+	// Звук мог быть остановлен сразу после регистрации — тогда он не запустится,
+	// а мониторинг освободит ресурсы.
+	control.play(TransitionOptions{})
 
+	// Шаг 5: Подписываемся на окончание и остановку звука.
+	monitorPlayback(soundCtx, closer, tracker, done, control)
+
+	// Громкость измеряется в фоне, чтобы длинный трек не задерживал старт.
+	if snd.measure {
+		go normalizeLater(filePath, params.TargetLUFS, player, masterMixer.rate())
+	}
+	return done, nil
+}
+
+// openedSound — открытый и декодируемый источник вместе с тегами.
+type openedSound struct {
+	stream   decodedStream
+	closer   io.Closer
+	normGain float64 // Усиление нормализации громкости (1 — без изменений)
+	measure  bool    // Громкость ещё не измерена, normGain пока 1
+	tags     Tags
+	cover    *Picture
+}
+
+// openSound открывает файл или URL, читает теги, выбирает декодер
+// и рассчитывает усиление нормализации, если для него есть тег или
+// измерение в кеше. Поток нужно закрыть через closer.
+func openSound(filePath string, params PlayParams) (*openedSound, error) {
+	// Шаг 1: Получаем доступ к данным (файл или сеть).
+	rs, closer, err := getReadSeeker(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Теги читаем до декодера, пока поток стоит в начале файла.
+	tags, cover := readMetadata(rs)
+	var replayGain float64
+	var hasReplayGain bool
+	if params.Normalize {
+		replayGain, hasReplayGain = parseReplayGain(tags["replaygain_track_gain"])
+	}
+
+	// Шаг 2: Инициализируем нужный декодер.
+	stream, err := getDecoder(rs, filePath)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+
+	// Нормализация громкости применяется как усиление перед обычной громкостью.
+	snd := &openedSound{stream: stream, closer: closer, normGain: 1, tags: tags, cover: cover}
+	if params.Normalize {
+		var measured bool
+		snd.normGain, measured = normalizationGain(filePath, params.TargetLUFS, replayGain, hasReplayGain)
+		snd.measure = !measured
+	}
+	return snd, nil
+}
+
+// newSoundVoice оборачивает поток в trackingStream с настройками из params,
+// создаёт для него голос микшера с частотой mixRate и перематывает на стартовую позицию.
+// Голос создаётся на паузе.
+func newSoundVoice(stream decodedStream, params PlayParams, normGain float64, mixRate int) (*trackingStream, *voice, error) {
+	tracker := &trackingStream{decodedStream: stream, playTails: !params.Loop, tailLeft: -1}
+	tracker.rate.speed = params.Speed
+	tracker.rate.pitch = params.Pitch
+	tracker.rate.preservePitch = params.PreservePitch
+	tracker.space.pan = params.Pan
+	if params.Spatial != nil {
+		sp := params.Spatial.withDefaults()
+		tracker.space.spatial = &sp
+	}
+	for _, p := range params.Effects {
+		if p != nil {
+			tracker.effects.add(p)
+		}
+	}
+	// Файл с другой частотой дискретизации пересчитывается под частоту микшера.
+	tracker.rate.srcRatio = float64(stream.SampleRate()) / float64(mixRate)
+	player := newVoice(tracker)
+	player.gain = normGain
+	player.loop = params.Loop
+
+	// Если включен FadeIn, начинаем с нуля, иначе ставим целевую громкость сразу
+	startVol := params.Volume
 	if params.FadeIn {
-		go fadeIn(player, params.Volume)
+		startVol = 0
 	}
+	player.SetVolume(startVol)
 
-	// Шаг 5: Запускаем фоновый мониторинг состояния плеера.
-	monitorPlayback(soundCtx, closer, stream, player, done, params)
-	return done, nil
+	// При обратном воспроизведении без стартовой позиции начинаем с конца трека
+	tracker.reverse = params.Reverse
+	if params.Reverse && params.Position == 0 {
+		if _, err := player.Seek(0, io.SeekEnd); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Если указана стартовая позиция — перематываем плеер
+	if params.Position > 0 {
+		offset := secondsToBytes(params.Position, stream.SampleRate())
+		if _, err := player.Seek(offset, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+	}
+	return tracker, player, nil
 }
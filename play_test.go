@@ -2,8 +2,12 @@ package playsound
 
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"io"
+	"math"
 	"os"
 	"testing"
 	"time"
@@ -44,36 +48,55 @@ func TestConversion(t *testing.T) {
 // Тест управления состояниями (имитация soundController)
 func TestSoundControlState(t *testing.T) {
 	done := make(chan struct{})
-	sc := soundController{
-		isPaused: false,
-	}
+	sc := &soundController{player: newVoice(nil), params: PlayParams{Volume: 1}, volume: 1}
 
 	// Имитируем регистрацию в карте (engine.go)
 	activeMu.Lock()
 	activeSounds[done] = sc
 	activeMu.Unlock()
+	defer func() {
+		activeMu.Lock()
+		delete(activeSounds, done)
+		activeMu.Unlock()
+	}()
 
-	// Проверяем получение через хелпер getControl
+	// Проверяем получение через хелпер getControl: это тот же контроллер, а не копия
 	ctrl, ok := getControl(done)
-	if !ok {
+	if !ok || ctrl != sc {
 		t.Fatal("Sound should be registered in activeSounds")
 	}
 
-	// Проверяем смену статуса паузы
-	ctrl.isPaused = true
-	activeMu.Lock()
-	activeSounds[done] = ctrl
-	activeMu.Unlock()
+	// Ещё не запущенный звук нельзя поставить на паузу
+	var terr *TransitionError
+	if err := Pause(done); !errors.As(err, &terr) || terr.From != StateIdle || terr.To != StatePaused {
+		t.Errorf("Pause() on idle sound = %v; want idle -> paused error", err)
+	}
 
-	ctrlUpdated, _ := getControl(done)
-	if !ctrlUpdated.isPaused {
-		t.Error("isPaused should be true after update")
+	if err := sc.play(TransitionOptions{}); err != nil || sc.State() != StatePlaying || !sc.player.IsPlaying() {
+		t.Fatalf("play(): err %v, state %v", err, sc.State())
+	}
+	if err := PlayOn(done); err == nil {
+		t.Error("PlayOn() on playing sound should fail")
 	}
 
-	// Очистка
-	activeMu.Lock()
-	delete(activeSounds, done)
-	activeMu.Unlock()
+	if err := Pause(done); err != nil || sc.State() != StatePaused || sc.player.IsPlaying() {
+		t.Errorf("Pause(): err %v, state %v, playing %v", err, sc.State(), sc.player.IsPlaying())
+	}
+	if err := Pause(done); err == nil {
+		t.Error("second Pause() should fail")
+	}
+
+	if err := PlayOn(done); err != nil || sc.State() != StatePlaying {
+		t.Errorf("PlayOn(): err %v, state %v", err, sc.State())
+	}
+
+	// Из конечного состояния выхода нет
+	sc.stopped()
+	for _, call := range []func(chan struct{}) error{Pause, PlayOn} {
+		if err := call(done); !errors.As(err, &terr) || terr.From != StateStopped {
+			t.Errorf("call on stopped sound = %v; want transition error", err)
+		}
+	}
 }
 
 func TestValidateParams(t *testing.T) {
@@ -135,7 +158,7 @@ func TestMonitorPlaybackClosing(t *testing.T) {
 	done := make(chan struct{})
 	stream := &mockStream{}
 	closer := &mockCloser{}
-	player := otoCtx.NewPlayer(stream)
+	player := newVoice(&trackingStream{decodedStream: stream})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -144,16 +167,17 @@ func TestMonitorPlaybackClosing(t *testing.T) {
 
 	// Регистрируем звук в системе
 	activeMu.Lock()
-	activeSounds[done] = soundController{
+	sc := &soundController{
 		cancel:     cancel,
 		player:     player,
 		params:     params,
 		sampleRate: 44100,
 	}
+	activeSounds[done] = sc
 	activeMu.Unlock()
 
 	// Запускаем мониторинг
-	monitorPlayback(ctx, closer, stream, player, done, params)
+	monitorPlayback(ctx, closer, stream, done, sc)
 
 	// Ждем закрытия канала done с таймаутом
 	select {
@@ -168,4 +192,296 @@ func TestMonitorPlaybackClosing(t *testing.T) {
 	case <-time.After(2 * time.Second):
 		t.Error("Таймаут: мониторинг не закрыл канал done вовремя")
 	}
-}
\ No newline at end of file
+}
+// ===================================================================
+// тест меток (cues.go)
+
+// pcmStream — поток из заранее подготовленных байт с заданной частотой.
+type pcmStream struct {
+	*bytes.Reader
+	rate int
+}
+
+func (s *pcmStream) SampleRate() int { return s.rate }
+
+func (s *pcmStream) Length() int64 { return s.Size() }
+
+func TestCueCrossing(t *testing.T) {
+	// 1 секунда тишины при частоте 1000 Гц = 4000 байт
+	ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(make([]byte, 4000)), 1000}}
+	ts.cues.add(secondsToBytes(0.5, 1000), 0.5, "beat", nil)
+	events := ts.cues.subscribe()
+
+	buf := make([]byte, 400)
+	readAll := func() {
+		for {
+			if _, err := ts.Read(buf); err != nil {
+				return
+			}
+		}
+	}
+
+	readAll()
+	if ev := <-events; ev.Name != "beat" || ev.Position != 0.5 {
+		t.Errorf("unexpected cue event %+v", ev)
+	}
+
+	// Перемотка назад (как при Loop) должна заново взвести метку
+	ts.Seek(0, io.SeekStart)
+	readAll()
+	if len(events) != 1 {
+		t.Errorf("cue should fire again after seeking back, got %d events", len(events))
+	}
+	<-events
+
+	// Перемотка вперёд через метку не должна её вызывать
+	ts.Seek(secondsToBytes(0.75, 1000), io.SeekStart)
+	readAll()
+	if len(events) != 0 {
+		t.Errorf("cue should not fire when seeking over it, got %d events", len(events))
+	}
+
+	ts.cues.close()
+	if _, ok := <-events; ok {
+		t.Error("events channel should be closed")
+	}
+}
+
+// Тест расчёта слышимой позиции с учётом задержки устройства (engine.go)
+func TestAudiblePosition(t *testing.T) {
+	SetOutputLatency(100 * time.Millisecond)
+	defer SetOutputLatency(defaultOutputLatency)
+
+	sc := &soundController{
+		sampleRate: 1000,
+		tracker:    &trackingStream{currentPos: secondsToBytes(1, 1000)},
+	}
+
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got != 0.9 {
+		t.Errorf("audiblePos() = %v s; want 0.9 s", got)
+	}
+
+	// На паузе устройство уже проиграло свой буфер
+	sc.state = StatePaused
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got != 1 {
+		t.Errorf("audiblePos() on pause = %v s; want 1 s", got)
+	}
+
+	// Позиция не уходит в минус в самом начале трека
+	sc.state = StatePlaying
+	sc.tracker.currentPos = 40
+	if got := sc.audiblePos(); got != 0 {
+		t.Errorf("audiblePos() = %d; want 0", got)
+	}
+}
+
+// На паузе позиция — последний смешанный кадр, а после снятия с паузы
+// она не уходит назад, пока в буфере нет нового звука голоса
+func TestAudiblePositionPauseResume(t *testing.T) {
+	SetOutputLatency(100 * time.Millisecond)
+	defer SetOutputLatency(defaultOutputLatency)
+
+	player := newTestVoice(2000)
+	sc := &soundController{sampleRate: 1000, player: player, tracker: player.source, state: StatePlaying}
+	player.mixInto(make([]float32, 2000), 0)
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got > 0.9 {
+		t.Errorf("audiblePos() = %v s; want at most 0.9 s", got)
+	}
+
+	player.Pause()
+	sc.state = StatePaused
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got != 1 {
+		t.Errorf("audiblePos() on pause = %v s; want 1 s", got)
+	}
+
+	player.Play()
+	sc.state = StatePlaying
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got != 1 {
+		t.Errorf("audiblePos() after resume = %v s; want 1 s", got)
+	}
+	player.mixInto(make([]float32, 400), 1000)
+	if got := bytesToSeconds(sc.audiblePos(), sc.sampleRate); got < 1 || got > 1.1 {
+		t.Errorf("audiblePos() = %v s; want 1..1.1 s", got)
+	}
+}
+
+// ===================================================================
+// тест скорости и высоты тона (speed.go)
+
+// sinePCM генерирует стерео-синус в формате int16 LE.
+func sinePCM(freq float64, rate int, seconds float64) []byte {
+	frames := int(float64(rate) * seconds)
+	buf := make([]float32, frames*2)
+	for i := 0; i < frames; i++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+		buf[i*2], buf[i*2+1] = v, v
+	}
+	out := make([]byte, frames*frameBytes)
+	floatsToBytes(buf, out)
+	return out
+}
+
+// renderStream читает поток до конца и возвращает кадры левого канала.
+func renderStream(t *testing.T, r io.Reader) []float32 {
+	t.Helper()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := make([]float32, len(data)/2)
+	bytesToFloats(data, frames)
+	left := make([]float32, len(frames)/2)
+	for i := range left {
+		left[i] = frames[i*2]
+	}
+	return left
+}
+
+// zeroCrossings считает смены знака — грубая оценка частоты сигнала.
+func zeroCrossings(s []float32) int {
+	n := 0
+	for i := 1; i < len(s); i++ {
+		if (s[i-1] < 0) != (s[i] < 0) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSpeedAndPitch(t *testing.T) {
+	const rate = 8000
+	pcm := sinePCM(440, rate, 1)
+	original := zeroCrossings(renderStream(t, bytes.NewReader(pcm)))
+
+	tests := []struct {
+		name          string
+		speed, pitch  float64
+		preserve      bool
+		wantLen       float64 // Ожидаемая длительность в секундах
+		wantFreqRatio float64 // Ожидаемое отношение частоты к исходной
+	}{
+		{"Double speed", 2, 0, false, 0.5, 2},
+		{"Half speed keeps pitch", 0.5, 0, true, 2, 1},
+		{"Octave up", 1, 12, false, 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), rate}}
+			ts.rate = rateStage{speed: tt.speed, pitch: tt.pitch, preservePitch: tt.preserve}
+			out := renderStream(t, ts)
+
+			seconds := float64(len(out)) / rate
+			if math.Abs(seconds-tt.wantLen) > 0.1*tt.wantLen {
+				t.Errorf("duration = %.3f s; want %.3f s", seconds, tt.wantLen)
+			}
+			freqRatio := float64(zeroCrossings(out)) / seconds / float64(original)
+			if math.Abs(freqRatio-tt.wantFreqRatio) > 0.1*tt.wantFreqRatio {
+				t.Errorf("frequency ratio = %.3f; want %.3f", freqRatio, tt.wantFreqRatio)
+			}
+			// Позиция считается в секундах исходного трека
+			if pos := bytesToSeconds(ts.CurrentPos(), rate); pos != 1 {
+				t.Errorf("source position = %v; want 1", pos)
+			}
+		})
+	}
+}
+
+// Тест обратного воспроизведения: кадры идут от конца к началу, позиция убывает
+func TestReversePlayback(t *testing.T) {
+	const frames = 1000
+	pcm := make([]byte, frames*frameBytes)
+	for i := 0; i < frames; i++ {
+		binary.LittleEndian.PutUint16(pcm[i*4:], uint16(i))
+		binary.LittleEndian.PutUint16(pcm[i*4+2:], uint16(i))
+	}
+
+	ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), 1000}}
+	ts.reverse = true
+	ts.cues.add(secondsToBytes(0.25, 1000), 0.25, "quarter", nil)
+	events := ts.cues.subscribe()
+
+	if err := ts.Rewind(); err != nil {
+		t.Fatal(err)
+	}
+	if ts.CurrentPos() != int64(len(pcm)) {
+		t.Fatalf("reverse playback should start at the end, got %d", ts.CurrentPos())
+	}
+
+	out, err := io.ReadAll(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(pcm) {
+		t.Fatalf("read %d bytes; want %d", len(out), len(pcm))
+	}
+	for i := 0; i < frames; i++ {
+		if got := binary.LittleEndian.Uint16(out[i*4:]); int(got) != frames-1-i {
+			t.Fatalf("frame %d = %d; want %d", i, got, frames-1-i)
+		}
+	}
+	if ts.CurrentPos() != 0 {
+		t.Errorf("position after reverse playback = %d; want 0", ts.CurrentPos())
+	}
+	if len(events) != 1 {
+		t.Errorf("cue should fire once in reverse, got %d events", len(events))
+	}
+
+	// Переключение обратно на прямое направление продолжает с той же позиции
+	ts.Seek(secondsToBytes(0.5, 1000), io.SeekStart)
+	if err := ts.setReverse(false); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	ts.Read(buf)
+	if got := binary.LittleEndian.Uint16(buf); got != 500 {
+		t.Errorf("forward frame after toggle = %d; want 500", got)
+	}
+}
+
+// writeTestWAV сохраняет PCM во временный WAV-файл (16 бит, стерео).
+func writeTestWAV(t *testing.T, pcm []byte, rate int) string {
+	t.Helper()
+	var hdr bytes.Buffer
+	hdr.WriteString("RIFF")
+	binary.Write(&hdr, binary.LittleEndian, uint32(36+len(pcm)))
+	hdr.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(2), uint32(rate), uint32(rate * 4), uint16(4), uint16(16)} {
+		binary.Write(&hdr, binary.LittleEndian, v)
+	}
+	hdr.WriteString("data")
+	binary.Write(&hdr, binary.LittleEndian, uint32(len(pcm)))
+	hdr.Write(pcm)
+
+	path := t.TempDir() + "/test.wav"
+	if err := os.WriteFile(path, hdr.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Звук проходит через микшер и по окончании отключается от шины
+func TestPlaybackThroughMixer(t *testing.T) {
+	if otoCtx == nil {
+		t.Skip("Пропуск: аудио-движок не инициализирован (нет аудиоустройства)")
+	}
+
+	path := writeTestWAV(t, sinePCM(440, 44100, 0.2), 44100)
+	done, err := PlaySoundWithParams(path, PlayParams{Volume: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: звук не завершился")
+	}
+
+	masterMixer.mu.Lock()
+	voices := len(masterMixer.voices)
+	masterMixer.mu.Unlock()
+	if voices != 0 {
+		t.Errorf("mixer should have no voices after playback, got %d", voices)
+	}
+}
@@ -0,0 +1,231 @@
+package playsound
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/youpy/go-wav"
+)
+
+// mp3SyncSearchLimit — сколько байт после тегов просматривать в поисках первого кадра MP3.
+const mp3SyncSearchLimit = 64 * 1024
+
+// ProbeInfo описывает аудиофайл без его воспроизведения.
+type ProbeInfo struct {
+	Format     string   // "mp3", "wav" или "flac" (FLAC только описывается, но не проигрывается)
+	SampleRate int      // Частота дискретизации, Гц
+	Channels   int      // Количество каналов в файле (при воспроизведении звук всегда стерео)
+	Bitrate    int      // Средний битрейт, бит/с
+	Duration   float64  // Длительность в секундах
+	Tags       Tags     // Текстовые теги: title, artist, album, track, year, genre и т. д.
+	Cover      *Picture // Встроенная обложка (nil, если её нет)
+}
+
+// WaveformPeak — минимум и максимум сигнала (моно-сумма каналов, -1..1) в одном отрезке трека.
+type WaveformPeak struct {
+	Min, Max float32
+}
+
+// Probe читает формат, длительность и теги файла или URL, не запуская аудио-движок.
+func Probe(source string) (*ProbeInfo, error) {
+	rs, closer, err := getReadSeeker(source)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Теги читаем до декодера: он сам перематывает поток
+	tags, cover := readMetadata(rs)
+	if sniffFormat(rs) == "flac" {
+		return probeFLAC(rs, size, tags, cover)
+	}
+
+	stream, err := getDecoder(rs, source)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ProbeInfo{SampleRate: stream.SampleRate(), Tags: tags, Cover: cover}
+
+	switch s := stream.(type) {
+	case *mp3Stream:
+		info.Format = "mp3"
+		info.Duration = bytesToSeconds(s.Length(), s.SampleRate())
+
+		var tagSize int64
+		if _, err := rs.Seek(0, io.SeekStart); err == nil {
+			if tag, _ := readID3v2(rs); tag != nil {
+				tagSize = tag.size
+			}
+		}
+		info.Channels = mp3Channels(rs, tagSize)
+		if info.Duration > 0 {
+			info.Bitrate = int(math.Round(float64(size-tagSize) * 8 / info.Duration))
+		}
+	case *wavWrapper:
+		info.Format = "wav"
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		r := wav.NewReader(rs.(readSeekerAt))
+		format, err := r.Format()
+		if err != nil {
+			return nil, err
+		}
+		info.Channels = int(format.NumChannels)
+		info.Bitrate = int(format.ByteRate) * 8
+		if d, err := r.Duration(); err == nil {
+			info.Duration = d.Seconds()
+		}
+	default:
+		info.Duration = bytesToSeconds(streamLength(stream), stream.SampleRate())
+	}
+	return info, nil
+}
+
+// probeFLAC описывает файл FLAC по блоку STREAMINFO, не декодируя звук.
+func probeFLAC(rs io.ReadSeeker, size int64, tags Tags, cover *Picture) (*ProbeInfo, error) {
+	flac, _ := readFLAC(rs, Tags{})
+	if flac == nil {
+		return nil, fmt.Errorf("invalid flac file")
+	}
+
+	info := &ProbeInfo{Format: "flac", SampleRate: flac.sampleRate, Channels: flac.channels, Tags: tags, Cover: cover}
+	if flac.sampleRate > 0 {
+		info.Duration = float64(flac.samples) / float64(flac.sampleRate)
+	}
+	if info.Duration > 0 {
+		info.Bitrate = int(math.Round(float64(size) * 8 / info.Duration))
+	}
+	return info, nil
+}
+
+// mp3Channels находит первый кадр MP3 после тегов и возвращает число каналов (1 или 2).
+// Если кадр не найден, считаем звук стерео — так его в любом случае выдаёт декодер.
+func mp3Channels(rs io.ReadSeeker, offset int64) int {
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return 2
+	}
+	buf := make([]byte, mp3SyncSearchLimit)
+	n, _ := io.ReadFull(rs, buf)
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if h, ok := parseMP3Header(buf[i : i+4]); ok {
+			return h.channels
+		}
+	}
+	return 2
+}
+
+// mp3Header — поля заголовка кадра MPEG Audio, нужные для описания файла.
+type mp3Header struct {
+	version  int // 1 — MPEG-1, 2 — MPEG-2, 25 — MPEG-2.5
+	layer    int
+	channels int
+}
+
+// parseMP3Header проверяет синхрослово и допустимость полей заголовка кадра.
+func parseMP3Header(b []byte) (mp3Header, bool) {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return mp3Header{}, false
+	}
+
+	var h mp3Header
+	switch (b[1] >> 3) & 3 {
+	case 0:
+		h.version = 25
+	case 2:
+		h.version = 2
+	case 3:
+		h.version = 1
+	default:
+		return mp3Header{}, false
+	}
+
+	layer := (b[1] >> 1) & 3
+	bitrate := b[2] >> 4
+	rate := (b[2] >> 2) & 3
+	if layer == 0 || bitrate == 0 || bitrate == 15 || rate == 3 {
+		return mp3Header{}, false
+	}
+	h.layer = 4 - int(layer)
+
+	h.channels = 2
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+	return h, true
+}
+
+// Waveform декодирует файл и делит его на buckets равных отрезков, возвращая
+// минимум и максимум сигнала в каждом — этого достаточно, чтобы нарисовать волну.
+// Аудио-движок не запускается.
+func Waveform(source string, buckets int) ([]WaveformPeak, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("buckets must be positive")
+	}
+
+	rs, closer, err := getReadSeeker(source)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	stream, err := getDecoder(rs, source)
+	if err != nil {
+		return nil, err
+	}
+
+	total := streamLength(stream) / frameBytes
+	if total <= 0 {
+		return nil, fmt.Errorf("unknown stream length")
+	}
+	return waveformOf(stream, total, buckets)
+}
+
+// waveformOf читает total кадров потока и раскладывает их по отрезкам.
+func waveformOf(stream io.Reader, total int64, buckets int) ([]WaveformPeak, error) {
+	peaks := make([]WaveformPeak, buckets)
+	seen := make([]bool, buckets)
+
+	raw := make([]byte, 4096*frameBytes)
+	frames := make([]float32, 4096*2)
+	var frame int64
+
+	for frame < total {
+		n, err := io.ReadFull(stream, raw)
+		got := n / frameBytes
+		bytesToFloats(raw[:got*frameBytes], frames[:got*2])
+
+		for i := 0; i < got && frame < total; i++ {
+			v := (frames[i*2] + frames[i*2+1]) / 2
+			b := int(frame * int64(buckets) / total)
+			if !seen[b] {
+				peaks[b] = WaveformPeak{Min: v, Max: v}
+				seen[b] = true
+			} else {
+				peaks[b].Min = min(peaks[b].Min, v)
+				peaks[b].Max = max(peaks[b].Max, v)
+			}
+			frame++
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return peaks, nil
+}
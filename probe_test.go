@@ -0,0 +1,96 @@
+package playsound
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// Тест описания WAV-файла без воспроизведения
+func TestProbeWAV(t *testing.T) {
+	const rate = 22050
+	path := writeTestWAV(t, sinePCM(440, rate, 1.5), rate)
+
+	info, err := Probe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "wav" || info.SampleRate != rate || info.Channels != 2 {
+		t.Errorf("Probe() = %+v; want wav, %d Hz, 2 channels", info, rate)
+	}
+	if info.Bitrate != rate*4*8 {
+		t.Errorf("bitrate = %d; want %d", info.Bitrate, rate*4*8)
+	}
+	if math.Abs(info.Duration-1.5) > 1e-3 {
+		t.Errorf("duration = %v; want 1.5", info.Duration)
+	}
+
+	if _, err := Probe(t.TempDir() + "/missing.wav"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+// Тест волны: синус даёт симметричные пики, тихая половина — нулевые
+func TestWaveform(t *testing.T) {
+	const rate = 8000
+	pcm := append(sinePCM(50, rate, 1), make([]byte, rate*frameBytes)...)
+
+	peaks, err := waveformOf(bytes.NewReader(pcm), int64(len(pcm)/frameBytes), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peaks) != 10 {
+		t.Fatalf("got %d buckets; want 10", len(peaks))
+	}
+	for i, p := range peaks {
+		if i < 5 && (p.Max < 0.45 || p.Min > -0.45) {
+			t.Errorf("bucket %d = %+v; want about ±0.5", i, p)
+		}
+		if i >= 5 && (p.Max != 0 || p.Min != 0) {
+			t.Errorf("silent bucket %d = %+v; want zeros", i, p)
+		}
+	}
+
+	if _, err := Waveform("missing.wav", 0); err == nil {
+		t.Error("expected error for zero buckets")
+	}
+}
+
+func TestParseMP3Header(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		ok       bool
+		channels int
+	}{
+		{"MPEG-1 Layer III stereo", []byte{0xff, 0xfb, 0x90, 0x00}, true, 2},
+		{"MPEG-1 Layer III mono", []byte{0xff, 0xfb, 0x90, 0xc0}, true, 1},
+		{"Bad bitrate", []byte{0xff, 0xfb, 0xf0, 0x00}, false, 0},
+		{"No sync", []byte{0x49, 0x44, 0x33, 0x03}, false, 0},
+	}
+	for _, tt := range tests {
+		h, ok := parseMP3Header(tt.header)
+		if ok != tt.ok || (ok && h.channels != tt.channels) {
+			t.Errorf("%s: parseMP3Header() = %+v, %v", tt.name, h, ok)
+		}
+	}
+}
+
+func TestID3TextFields(t *testing.T) {
+	tag, err := readID3v2(bytes.NewReader(id3v23(
+		[2]string{"TIT2", "\x00Song"},
+		[2]string{"TPE1", "\x03Артист"},
+		[2]string{"TRCK", "\x003/12"},
+		[2]string{"TXXX", "\x00MOOD\x00calm"},
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := tag.textFields()
+	want := map[string]string{"title": "Song", "artist": "Артист", "track": "3/12", "mood": "calm"}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("fields[%q] = %q; want %q", k, fields[k], v)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package playsound
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	renderChunk   = 1024 // Сколько кадров микшер отдаёт за один шаг рендеринга
+	wavHeaderSize = 44
+)
+
+// Renderer смешивает звуки без аудиоустройства и записывает результат в WAV
+// (16 бит, стерео). Звуки проходят ту же обработку, что и при воспроизведении:
+// скорость, эффекты, панораму, нормализацию и лимитер на шине.
+type Renderer struct {
+	w      io.WriteSeeker
+	mix    *mixer
+	frames int64 // Сколько кадров уже записано
+	buf    []byte
+}
+
+// NewRenderer создаёт рендерер, пишущий в w с частотой sampleRate.
+// После рендеринга нужно вызвать Close, чтобы записать размеры в заголовок WAV.
+func NewRenderer(w io.WriteSeeker, sampleRate int) (*Renderer, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if err := writeWAVHeader(w, sampleRate, 0); err != nil {
+		return nil, err
+	}
+
+	m := newMixer()
+	m.sampleRate = sampleRate
+	return &Renderer{w: w, mix: m, buf: make([]byte, renderChunk*frameBytes)}, nil
+}
+
+// Render проигрывает звук от начала до конца (вместе с хвостами эффектов)
+// и дописывает результат в файл. Loop игнорируется: трек звучит один раз.
+func (r *Renderer) Render(source string, params PlayParams) error {
+	params = validateParams(params)
+	params.Loop = false
+
+	snd, err := openSound(source, params)
+	if err != nil {
+		return err
+	}
+	defer snd.closer.Close()
+
+	// Рендерингу некуда спешить: громкость измеряется до начала
+	if snd.measure {
+		if snd.normGain, err = measureStream(source, snd.stream, params.TargetLUFS); err != nil {
+			return err
+		}
+	}
+
+	tracker, player, err := newSoundVoice(snd.stream, params, snd.normGain, r.mix.sampleRate)
+	if err != nil {
+		return err
+	}
+	defer tracker.cues.close()
+	defer tracker.meter.close()
+
+	r.mix.add(player)
+	defer r.mix.remove(player)
+	player.Play()
+
+	// Плавный старт длится FadeInTime, как и при воспроизведении
+	var rendered int64
+	for player.IsPlaying() {
+		if params.FadeIn {
+			elapsed := float64(rendered) / float64(r.mix.sampleRate)
+			player.SetVolume(params.Volume * min(elapsed/params.FadeInTime.Seconds(), 1))
+		}
+
+		start := r.mix.frames
+		n, _ := r.mix.Read(r.buf)
+		// Последняя порция пишется только до конца звука, без тишины за ним
+		if !player.IsPlaying() {
+			n = int(player.endFrame()-start) * frameBytes
+		}
+		if _, err := r.w.Write(r.buf[:n]); err != nil {
+			return err
+		}
+		rendered += int64(n / frameBytes)
+	}
+	r.frames += rendered
+	return nil
+}
+
+// Duration возвращает длительность уже записанного звука.
+func (r *Renderer) Duration() time.Duration {
+	return time.Duration(float64(r.frames) / float64(r.mix.sampleRate) * float64(time.Second))
+}
+
+// Close записывает итоговые размеры в заголовок WAV. Сам w не закрывается.
+func (r *Renderer) Close() error {
+	if _, err := r.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeWAVHeader(r.w, r.mix.sampleRate, r.frames*frameBytes); err != nil {
+		return err
+	}
+	_, err := r.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// RenderToFile записывает один звук в WAV-файл path.
+func RenderToFile(source, path string, params PlayParams, sampleRate int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := NewRenderer(f, sampleRate)
+	if err != nil {
+		return err
+	}
+	if err := r.Render(source, params); err != nil {
+		return err
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeWAVHeader пишет заголовок PCM WAV (16 бит, стерео) для dataSize байт данных.
+func writeWAVHeader(w io.Writer, sampleRate int, dataSize int64) error {
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+dataSize))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(h[22:], 2)
+	binary.LittleEndian.PutUint32(h[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(sampleRate*frameBytes))
+	binary.LittleEndian.PutUint16(h[32:], frameBytes)
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(dataSize))
+	_, err := w.Write(h)
+	return err
+}
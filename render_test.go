@@ -0,0 +1,87 @@
+package playsound
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// Тест рендеринга в файл: без аудиоустройства получается WAV той же длительности
+func TestRenderToFile(t *testing.T) {
+	const rate = 22050
+	src := writeTestWAV(t, sinePCM(440, rate, 1), rate)
+	out := t.TempDir() + "/out.wav"
+
+	if err := RenderToFile(src, out, PlayParams{Volume: 0.5, Speed: 2}, 44100); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Probe(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "wav" || info.SampleRate != 44100 || info.Channels != 2 {
+		t.Errorf("Probe() = %+v; want 44100 Hz stereo wav", info)
+	}
+	// Вдвое быстрее — вдвое короче (с точностью до одного блока микшера)
+	if math.Abs(info.Duration-0.5) > float64(renderChunk)/44100+0.01 {
+		t.Errorf("duration = %.3f s; want about 0.5 s", info.Duration)
+	}
+
+	peaks, err := Waveform(out, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := peaks[1]; p.Max < 0.2 || p.Max > 0.3 {
+		t.Errorf("peak = %.3f; want about 0.25 (volume 0.5 of a 0.5 sine)", p.Max)
+	}
+
+	if err := RenderToFile("missing.wav", out, PlayParams{}, 44100); err == nil {
+		t.Error("expected error for missing source")
+	}
+}
+
+// Отрендеренный файл ровно той же длины, что и источник: последняя порция
+// микшера не дописывает тишину, и между треками она не накапливается
+func TestRenderLength(t *testing.T) {
+	const rate = 44100
+	src := writeTestWAV(t, sinePCM(440, rate, 10000.0/rate), rate)
+	out := t.TempDir() + "/out.wav"
+
+	snd, err := openSound(src, PlayParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := streamLength(snd.stream) / frameBytes
+	snd.closer.Close()
+
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := NewRenderer(f, rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := r.Render(src, PlayParams{Volume: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (info.Size() - wavHeaderSize) / frameBytes; got != 2*frames {
+		t.Errorf("rendered %d frames; want %d", got, 2*frames)
+	}
+	if got, want := r.Duration(), time.Duration(2*frames)*time.Second/rate; got != want {
+		t.Errorf("Duration() = %v; want %v", got, want)
+	}
+}
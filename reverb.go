@@ -0,0 +1,241 @@
+package playsound
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// maxTail ограничивает длину хвоста эффекта, чтобы звук с почти бесконечной
+// обратной связью всё-таки завершился.
+const maxTail = 10 * time.Second
+
+// Настройки Freeverb: длины гребенчатых и всепропускающих фильтров в семплах при 44100 Гц.
+var (
+	reverbCombTuning    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllpassTuning = []int{556, 441, 341, 225}
+)
+
+const (
+	reverbStereoSpread = 23    // Сдвиг длин фильтров правого канала для стереоэффекта
+	reverbInputGain    = 0.015 // Входное ослабление, чтобы сумма гребенок не перегружала выход
+)
+
+// ReverbParams задаёт параметры алгоритмической реверберации.
+type ReverbParams struct {
+	RoomSize float64 // Размер помещения 0..1 — чем больше, тем длиннее хвост
+	Damping  float64 // Поглощение высоких частот 0..1
+	Wet      float64 // Доля обработанного сигнала 0..1 (0 — только исходный)
+}
+
+// combFilter — гребенчатый фильтр с затуханием высоких частот в обратной связи.
+type combFilter struct {
+	buf   []float32
+	pos   int
+	store float32
+}
+
+func (c *combFilter) process(in, feedback, damp float32) float32 {
+	out := c.buf[c.pos]
+	c.store = out*(1-damp) + c.store*damp
+	c.buf[c.pos] = in + c.store*feedback
+	c.pos = (c.pos + 1) % len(c.buf)
+	return out
+}
+
+// allpassFilter размывает отражения, не меняя АЧХ.
+type allpassFilter struct {
+	buf []float32
+	pos int
+}
+
+func (a *allpassFilter) process(in float32) float32 {
+	bufOut := a.buf[a.pos]
+	a.buf[a.pos] = in + bufOut*0.5
+	a.pos = (a.pos + 1) % len(a.buf)
+	return bufOut - in
+}
+
+// Reverb — реверберация по схеме Freeverb: параллельные гребенчатые фильтры
+// и последовательные всепропускающие для каждого канала.
+type Reverb struct {
+	mu         sync.Mutex
+	params     ReverbParams
+	sampleRate int
+	combs      [2][]combFilter
+	allpasses  [2][]allpassFilter
+}
+
+// NewReverb создаёт реверберацию с заданными параметрами.
+func NewReverb(params ReverbParams) *Reverb {
+	return &Reverb{params: clampReverb(params)}
+}
+
+func clampReverb(p ReverbParams) ReverbParams {
+	p.RoomSize = clamp01(p.RoomSize)
+	p.Damping = clamp01(p.Damping)
+	p.Wet = clamp01(p.Wet)
+	return p
+}
+
+func clamp01(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
+
+// SetParams меняет параметры реверберации во время воспроизведения.
+func (r *Reverb) SetParams(params ReverbParams) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.params = clampReverb(params)
+}
+
+// Params возвращает текущие параметры.
+func (r *Reverb) Params() ReverbParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.params
+}
+
+// feedback переводит размер помещения в коэффициент обратной связи гребенок.
+func (p ReverbParams) feedback() float64 {
+	return 0.7 + 0.28*p.RoomSize
+}
+
+// allocate создаёт буферы фильтров под частоту дискретизации.
+func (r *Reverb) allocate(sampleRate int) {
+	scale := float64(sampleRate) / 44100
+	for ch := 0; ch < 2; ch++ {
+		spread := ch * reverbStereoSpread
+		r.combs[ch] = make([]combFilter, len(reverbCombTuning))
+		for i, n := range reverbCombTuning {
+			r.combs[ch][i].buf = make([]float32, max(1, int(float64(n+spread)*scale)))
+		}
+		r.allpasses[ch] = make([]allpassFilter, len(reverbAllpassTuning))
+		for i, n := range reverbAllpassTuning {
+			r.allpasses[ch][i].buf = make([]float32, max(1, int(float64(n+spread)*scale)))
+		}
+	}
+	r.sampleRate = sampleRate
+}
+
+// Process реализует Processor.
+func (r *Reverb) Process(frames []float32, sampleRate int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sampleRate != sampleRate {
+		r.allocate(sampleRate)
+	}
+
+	feedback := float32(r.params.feedback())
+	damp := float32(r.params.Damping * 0.4)
+	wet := float32(r.params.Wet)
+
+	for i := 0; i+1 < len(frames); i += 2 {
+		in := (frames[i] + frames[i+1]) * reverbInputGain
+		for ch := 0; ch < 2; ch++ {
+			var out float32
+			for c := range r.combs[ch] {
+				out += r.combs[ch][c].process(in, feedback, damp)
+			}
+			for a := range r.allpasses[ch] {
+				out = r.allpasses[ch][a].process(out)
+			}
+			frames[i+ch] = frames[i+ch]*(1-wet) + out*wet*3
+		}
+	}
+}
+
+// Tail оценивает время затухания отражений до -60 дБ.
+func (r *Reverb) Tail() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	longest := float64(reverbCombTuning[len(reverbCombTuning)-1]+reverbStereoSpread) / 44100
+	return decayTime(longest, r.params.feedback())
+}
+
+// decayTime рассчитывает, за сколько проходов через линию задержки period
+// сигнал с обратной связью feedback затухнет до -60 дБ.
+func decayTime(period, feedback float64) time.Duration {
+	if feedback <= 0 {
+		return time.Duration(period * float64(time.Second))
+	}
+	if feedback >= 1 {
+		return maxTail
+	}
+	passes := math.Log(0.001) / math.Log(feedback)
+	d := time.Duration((passes + 1) * period * float64(time.Second))
+	return min(d, maxTail)
+}
+
+// DelayParams задаёт параметры эха.
+type DelayParams struct {
+	Time     time.Duration // Задержка между повторами
+	Feedback float64       // Доля сигнала, возвращаемая в линию задержки 0..0.95
+	Mix      float64       // Доля эха в выходном сигнале 0..1
+}
+
+// Delay — эхо с обратной связью на кольцевом буфере.
+type Delay struct {
+	mu     sync.Mutex
+	params DelayParams
+	buf    []float32
+	pos    int
+}
+
+// NewDelay создаёт эффект эха с заданными параметрами.
+func NewDelay(params DelayParams) *Delay {
+	return &Delay{params: clampDelay(params)}
+}
+
+func clampDelay(p DelayParams) DelayParams {
+	if p.Time < time.Millisecond {
+		p.Time = time.Millisecond
+	}
+	p.Feedback = math.Min(math.Max(p.Feedback, 0), 0.95)
+	p.Mix = clamp01(p.Mix)
+	return p
+}
+
+// SetParams меняет параметры эха. При изменении времени задержки буфер очищается.
+func (d *Delay) SetParams(params DelayParams) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.params = clampDelay(params)
+}
+
+// Params возвращает текущие параметры.
+func (d *Delay) Params() DelayParams {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.params
+}
+
+// Process реализует Processor.
+func (d *Delay) Process(frames []float32, sampleRate int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	size := max(1, int(d.params.Time.Seconds()*float64(sampleRate))) * 2
+	if len(d.buf) != size {
+		d.buf = make([]float32, size)
+		d.pos = 0
+	}
+
+	feedback := float32(d.params.Feedback)
+	mix := float32(d.params.Mix)
+	for i := range frames {
+		echo := d.buf[d.pos]
+		d.buf[d.pos] = frames[i] + echo*feedback
+		d.pos = (d.pos + 1) % len(d.buf)
+		frames[i] = frames[i]*(1-mix) + echo*mix
+	}
+}
+
+// Tail возвращает время, за которое повторы затухнут до -60 дБ.
+func (d *Delay) Tail() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return decayTime(d.params.Time.Seconds(), d.params.Feedback)
+}
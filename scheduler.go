@@ -0,0 +1,120 @@
+package playsound
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// timer — отложенная задача планировщика.
+type timer struct {
+	at    time.Time
+	fn    func()
+	index int // Позиция в куче; -1, если задача уже выполнена или отменена
+}
+
+// timerHeap — очередь задач, упорядоченная по времени запуска.
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
+
+// scheduler выполняет отложенные задачи (шаги плавных изменений громкости,
+// завершение звуков) в одной горутине, сколько бы звуков ни играло.
+// Задачи должны быть короткими и не блокироваться: пока одна выполняется,
+// остальные ждут.
+type scheduler struct {
+	mu     sync.Mutex
+	timers timerHeap
+	wake   chan struct{}
+	start  sync.Once
+}
+
+var sched = newScheduler()
+
+func newScheduler() *scheduler {
+	return &scheduler{wake: make(chan struct{}, 1)}
+}
+
+// after выполняет fn через d. Горутина планировщика запускается при первом вызове.
+func (s *scheduler) after(d time.Duration, fn func()) *timer {
+	s.start.Do(func() { go s.run() })
+
+	t := &timer{at: time.Now().Add(d), fn: fn}
+	s.mu.Lock()
+	heap.Push(&s.timers, t)
+	first := t.index == 0
+	s.mu.Unlock()
+
+	// Будим планировщик, только если новая задача стала ближайшей
+	if first {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return t
+}
+
+// cancel отменяет задачу. Возвращает false, если она уже выполнена или отменена.
+func (s *scheduler) cancel(t *timer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&s.timers, t.index)
+	return true
+}
+
+func (s *scheduler) run() {
+	wait := time.NewTimer(time.Hour)
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		var due []*timer
+		for len(s.timers) > 0 && !s.timers[0].at.After(now) {
+			due = append(due, heap.Pop(&s.timers).(*timer))
+		}
+		next := time.Hour
+		if len(s.timers) > 0 {
+			next = s.timers[0].at.Sub(now)
+		}
+		s.mu.Unlock()
+
+		for _, t := range due {
+			t.fn()
+		}
+		if len(due) > 0 {
+			// Задачи могли запланировать новые — пересчитываем ближайшую
+			continue
+		}
+
+		wait.Reset(next)
+		select {
+		case <-wait.C:
+		case <-s.wake:
+			wait.Stop()
+		}
+	}
+}
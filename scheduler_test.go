@@ -0,0 +1,232 @@
+package playsound
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerOrder(t *testing.T) {
+	s := newScheduler()
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for _, d := range []int{30, 10, 20} {
+		wg.Add(1)
+		s.after(time.Duration(d)*time.Millisecond, func() {
+			mu.Lock()
+			order = append(order, d)
+			mu.Unlock()
+			wg.Done()
+		})
+	}
+	wg.Wait()
+	if fmt.Sprint(order) != "[10 20 30]" {
+		t.Errorf("order = %v; want [10 20 30]", order)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := newScheduler()
+	fired := make(chan struct{}, 1)
+	tm := s.after(20*time.Millisecond, func() { fired <- struct{}{} })
+	if !s.cancel(tm) {
+		t.Fatal("cancel() = false for pending timer")
+	}
+	if s.cancel(tm) {
+		t.Error("second cancel() = true")
+	}
+
+	done := make(chan struct{})
+	s.after(40*time.Millisecond, func() { close(done) })
+	<-done
+	select {
+	case <-fired:
+		t.Error("cancelled timer fired")
+	default:
+	}
+}
+
+// newTestVoice создаёт играющий голос из frames кадров тишины с частотой 1000 Гц.
+func newTestVoice(frames int) *voice {
+	v := newVoice(&trackingStream{decodedStream: &pcmStream{bytes.NewReader(make([]byte, frames*frameBytes)), 1000}})
+	v.Play()
+	return v
+}
+
+// Конец потока сообщает сам голос, как только микшер дочитал его до конца
+func TestVoiceEndHook(t *testing.T) {
+	v := newTestVoice(150)
+	ends := 0
+	if !v.watchEnd(func() { ends++ }) {
+		t.Fatal("watchEnd() = false for playing voice")
+	}
+
+	buf := make([]float32, 200)
+	v.mixInto(buf, 0)
+	if ends != 0 || !v.IsPlaying() {
+		t.Fatalf("after 100 frames: ends %d, playing %v", ends, v.IsPlaying())
+	}
+	v.mixInto(buf, 0)
+	v.mixInto(buf, 0)
+	if ends != 1 || v.IsPlaying() {
+		t.Errorf("after end: ends %d, playing %v; want 1, false", ends, v.IsPlaying())
+	}
+	if v.watchEnd(func() {}) {
+		t.Error("watchEnd() = true for finished voice")
+	}
+}
+
+// Зацикленный голос перематывается при чтении и заполняет буфер без пропусков
+func TestVoiceLoopInReadPath(t *testing.T) {
+	v := newTestVoice(30)
+	v.loop = true
+	v.watchEnd(func() { t.Error("looping voice should not end") })
+
+	buf := make([]float32, 200)
+	for range 5 {
+		v.mixInto(buf, 0)
+	}
+	if !v.IsPlaying() {
+		t.Error("looping voice stopped")
+	}
+
+	empty := newTestVoice(0)
+	empty.loop = true
+	ended := false
+	empty.watchEnd(func() { ended = true })
+	empty.mixInto(buf, 0)
+	if !ended || empty.IsPlaying() {
+		t.Errorf("empty looping voice: ended %v, playing %v; want true, false", ended, empty.IsPlaying())
+	}
+}
+
+func TestFadeScheduled(t *testing.T) {
+	sc := &soundController{player: newVoice(nil), state: StatePaused, volume: 0.1}
+	gen, _ := sc.switchTo(StateFading, StatePlaying, func() { sc.player.SetVolume(0) })
+	finished := make(chan bool, 1)
+	sc.fade(gen, 50*time.Millisecond, nil, func(ok bool) { finished <- ok })
+	select {
+	case ok := <-finished:
+		if !ok {
+			t.Error("fade-in should finish")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Таймаут: нарастание не завершилось")
+	}
+	if v := sc.player.Volume(); v != 0.1 || sc.State() != StatePlaying {
+		t.Errorf("after fade-in: volume %v, state %v; want 0.1, playing", v, sc.State())
+	}
+
+	// Прерванное затухание не трогает громкость
+	gen, _ = sc.switchTo(StateFading, StatePaused, nil)
+	sc.fade(gen, time.Hour, nil, func(ok bool) { finished <- ok })
+	sc.switchTo(StatePlaying, 0, nil)
+	if <-finished {
+		t.Error("cancelled fade reported success")
+	}
+	if v := sc.player.Volume(); v != 0.1 {
+		t.Errorf("cancelled fade-out changed volume to %v", v)
+	}
+}
+
+// benchmarkEnd проигрывает sounds коротких звуков через микшер и ждёт,
+// пока о конце каждого станет известно. watch подписывается на конец голоса.
+func benchmarkEnd(b *testing.B, sounds int, watch func(v *voice, ended func())) {
+	m := newMixer()
+	m.sampleRate = 1000
+	buf := make([]byte, 100*frameBytes)
+
+	for b.Loop() {
+		var wg sync.WaitGroup
+		wg.Add(sounds)
+		for range sounds {
+			v := newTestVoice(250)
+			m.add(v)
+			watch(v, wg.Done)
+		}
+		goroutines := runtime.NumGoroutine()
+		allEnded := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(allEnded)
+		}()
+
+	mixing:
+		for {
+			m.Read(buf)
+			runtime.Gosched() // Даём поработать планировщику и горутинам опроса
+			select {
+			case <-allEnded:
+				break mixing
+			default:
+			}
+		}
+		b.ReportMetric(float64(goroutines), "goroutines")
+
+		m.mu.Lock()
+		m.voices = m.voices[:0]
+		m.mu.Unlock()
+	}
+}
+
+// BenchmarkEndOfStream сравнивает событийное определение конца потока
+// с прежним опросом IsPlaying раз в 100 мс из отдельной горутины на каждый звук.
+func BenchmarkEndOfStream(b *testing.B) {
+	for _, sounds := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("events/sounds=%d", sounds), func(b *testing.B) {
+			benchmarkEnd(b, sounds, func(v *voice, ended func()) {
+				v.watchEnd(func() { sched.after(0, ended) })
+			})
+		})
+		b.Run(fmt.Sprintf("polling/sounds=%d", sounds), func(b *testing.B) {
+			benchmarkEnd(b, sounds, func(v *voice, ended func()) {
+				go func() {
+					for v.IsPlaying() {
+						time.Sleep(100 * time.Millisecond)
+					}
+					ended()
+				}()
+			})
+		})
+	}
+}
+
+// blockingCloser закрывается, только когда тест отпустит release.
+type blockingCloser struct{ release chan struct{} }
+
+func (c *blockingCloser) Close() error {
+	<-c.release
+	return nil
+}
+
+// Медленное освобождение ресурсов закончившегося звука не задерживает
+// остальные задачи планировщика
+func TestFinishOffScheduler(t *testing.T) {
+	player := newVoice(&trackingStream{decodedStream: &mockStream{}})
+	closer := &blockingCloser{release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	monitorPlayback(ctx, closer, player.source, done, &soundController{cancel: cancel, player: player})
+
+	time.Sleep(masterBufferDuration + 50*time.Millisecond)
+	ran := make(chan struct{})
+	sched.after(0, func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler is blocked by Close")
+	}
+
+	close(closer.release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Таймаут: done не закрыт")
+	}
+}
@@ -0,0 +1,77 @@
+package playsound
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Перемотка с плавностью: голос затихает до нуля, перематывается на кадре
+// тишины и нарастает обратно
+func TestSeekFade(t *testing.T) {
+	v := constantVoice(1000)
+	buf := make([]float32, 200)
+	v.mixInto(buf, 0)
+
+	if pos, err := v.seekSmooth(500*frameBytes, 20); err != nil || pos != 500*frameBytes {
+		t.Fatalf("seekSmooth() = %d, %v", pos, err)
+	}
+	buf = make([]float32, 160)
+	v.mixInto(buf, 100)
+
+	for i := 1; i < 20; i++ {
+		if buf[i*2] >= buf[(i-1)*2] {
+			t.Fatalf("frame %d: %v after %v; want fading out", i, buf[i*2], buf[(i-1)*2])
+		}
+	}
+	if buf[19*2] != 0 {
+		t.Errorf("last faded frame = %v; want silence", buf[19*2])
+	}
+	for i := 21; i < 40; i++ {
+		if buf[i*2] <= buf[(i-1)*2] {
+			t.Fatalf("frame %d: %v after %v; want fading in", i, buf[i*2], buf[(i-1)*2])
+		}
+	}
+	if buf[39*2] != 0.5 || buf[79*2] != 0.5 {
+		t.Errorf("frames after fade-in = %v, %v; want 0.5", buf[39*2], buf[79*2])
+	}
+	if pos := v.source.CurrentPos(); pos != 560*frameBytes {
+		t.Errorf("position = %d frames; want 560", pos/frameBytes)
+	}
+}
+
+func TestSeekClampAndRelative(t *testing.T) {
+	done, sc := startTestSound(t, PlayParams{Volume: 0.5})
+	defer Stop(done)
+	sc.totalBytes = 100 * frameBytes
+	for voiceField(sc.player, func(v *voice) float64 { return float64(v.started) }) < 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if frame, err := SeekFrame(done, 1000); err != nil || frame != 100 {
+		t.Errorf("SeekFrame(1000) = %d, %v; want 100 (track end)", frame, err)
+	}
+	if pos, err := SeekWithOptions(done, -5, SeekOptions{}); err != nil || pos != 0 {
+		t.Errorf("Seek(-5) = %v, %v; want 0", pos, err)
+	}
+	if _, err := SeekWithOptions(done, math.NaN(), SeekOptions{}); err == nil {
+		t.Error("Seek(NaN) should fail")
+	}
+
+	// Пока перемотка ждёт затухания, относительная перемотка отсчитывается от её цели
+	if pos, err := SeekWithOptions(done, 0.05, SeekOptions{Fade: time.Hour}); err != nil || pos != 0.05 {
+		t.Fatalf("SeekWithOptions(0.05) = %v, %v", pos, err)
+	}
+	if pos, err := SeekBy(done, 0.01); err != nil || pos != 0.06 {
+		t.Errorf("SeekBy(0.01) = %v, %v; want 0.06", pos, err)
+	}
+	if pos, err := SeekBy(done, -1); err != nil || pos != 0 {
+		t.Errorf("SeekBy(-1) = %v, %v; want 0", pos, err)
+	}
+	if err := Seek(done, 0.02); err != nil {
+		t.Fatal(err)
+	}
+	if _, pending := sc.player.pendingSeek(); pending {
+		t.Error("immediate Seek should cancel the pending one")
+	}
+}
@@ -0,0 +1,586 @@
+// Package server позволяет управлять библиотекой playsound из других процессов
+// по HTTP/JSON — через TCP-порт или Unix-сокет. События жизненного цикла звуков
+// (запуск, пауза, остановка, окончание) рассылаются как server-sent events.
+//
+// Маршруты:
+//
+//	POST /sounds                  запустить звук: {"source": "...", "volume": 0.8, ...} → {"id": 1}
+//	GET  /sounds                  список активных звуков, в том числе запущенных напрямую через библиотеку
+//	GET  /sounds/{id}             состояние звука
+//	GET  /sounds/{id}/position    позиция и длительность в секундах
+//	POST /sounds/{id}/stop        остановить звук: необязательно {"fade_ms": 500, "no_fade": false, "wait": true}
+//	POST /sounds/{id}/pause       пауза: необязательно {"fade_ms": 300, "no_fade": false, "wait": true}
+//	POST /sounds/{id}/resume      продолжить: параметры плавности те же, что у паузы
+//	POST /sounds/{id}/seek        перемотка: {"position": 12.5, "relative": false, "fade_ms": 30} → {"position": 12.5}
+//	POST /sounds/{id}/volume      громкость: {"volume": 0.5}
+//	POST /stop-all                остановить все звуки: параметры те же, что у остановки звука
+//	GET  /events                  поток событий (text/event-stream)
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Roman77St/playsound"
+)
+
+// eventsBuffer — размер буфера событий одного подписчика.
+// Если подписчик не успевает читать, лишние события отбрасываются.
+const eventsBuffer = 32
+
+// Типы событий жизненного цикла.
+const (
+	EventStarted = "started"
+	EventPaused  = "paused"  // Звук встал на паузу (после затухания, если оно было)
+	EventResumed = "resumed" // Звук снова играет в полную громкость
+	EventStopped = "stopped" // Звук остановлен командой
+	EventEnded   = "ended"   // Звук доиграл до конца
+)
+
+// Event — событие жизненного цикла звука.
+type Event struct {
+	Type   string    `json:"type"`
+	ID     int       `json:"id"`
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// PlayRequest — тело запроса на запуск звука. Поля соответствуют playsound.PlayParams.
+type PlayRequest struct {
+	Source        string  `json:"source"`
+	Volume        float64 `json:"volume"`
+	Loop          bool    `json:"loop"`
+	FadeIn        bool    `json:"fade_in"`
+	FadeOut       bool    `json:"fade_out"`
+	Position      float64 `json:"position"`
+	Speed         float64 `json:"speed"`
+	Pitch         float64 `json:"pitch"`
+	PreservePitch bool    `json:"preserve_pitch"`
+	Reverse       bool    `json:"reverse"`
+	Normalize     bool    `json:"normalize"`
+	TargetLUFS    float64 `json:"target_lufs"`
+	Pan           float64 `json:"pan"`
+	Group         string  `json:"group"`
+	Priority      int     `json:"priority"`
+}
+
+func (r PlayRequest) params() playsound.PlayParams {
+	return playsound.PlayParams{
+		Volume:        r.Volume,
+		Loop:          r.Loop,
+		FadeIn:        r.FadeIn,
+		FadeOut:       r.FadeOut,
+		Position:      r.Position,
+		Speed:         r.Speed,
+		Pitch:         r.Pitch,
+		PreservePitch: r.PreservePitch,
+		Reverse:       r.Reverse,
+		Normalize:     r.Normalize,
+		TargetLUFS:    r.TargetLUFS,
+		Pan:           r.Pan,
+		Group:         r.Group,
+		Priority:      r.Priority,
+	}
+}
+
+// SoundInfo — состояние звука в ответах сервера.
+type SoundInfo struct {
+	ID       int     `json:"id"`
+	Source   string  `json:"source"`
+	Group    string  `json:"group,omitempty"`
+	State    string  `json:"state"` // playing, fading, paused или stopped
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+	Volume   float64 `json:"volume"`
+	Paused   bool    `json:"paused"`
+}
+
+// soundInfo переводит снимок состояния из библиотеки в ответ сервера.
+func soundInfo(id int, info playsound.SoundInfo) SoundInfo {
+	return SoundInfo{
+		ID:       id,
+		Source:   info.Source,
+		Group:    info.Group,
+		State:    info.State.String(),
+		Position: info.Position,
+		Duration: info.Duration,
+		Volume:   info.Volume,
+		Paused:   info.State == playsound.StatePaused,
+	}
+}
+
+// sound — звук, которому сервер выдал идентификатор.
+type sound struct {
+	id      int
+	source  string
+	done    chan struct{}
+	stopped bool // Остановлен командой, а не закончился сам
+}
+
+// Server выдаёт звукам числовые идентификаторы (канал done нельзя передать
+// другому процессу) и реализует http.Handler. Состояние звуков сервер не
+// хранит, а берёт из библиотеки, поэтому видит и звуки, запущенные или
+// приостановленные в обход него.
+type Server struct {
+	mu     sync.Mutex
+	nextID int
+	sounds map[int]*sound
+	ids    map[chan struct{}]int // Идентификатор по каналу done: перезапуск источника возвращает прежний канал
+	subs   map[chan Event]struct{}
+	mux    *http.ServeMux
+}
+
+// New создаёт сервер управления.
+func New() *Server {
+	s := &Server{
+		sounds: make(map[int]*sound),
+		ids:    make(map[chan struct{}]int),
+		subs:   make(map[chan Event]struct{}),
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("POST /sounds", s.handlePlay)
+	s.mux.HandleFunc("GET /sounds", s.handleList)
+	s.mux.HandleFunc("GET /sounds/{id}", s.withSound(s.handleInfo))
+	s.mux.HandleFunc("GET /sounds/{id}/position", s.withSound(s.handlePosition))
+	s.mux.HandleFunc("POST /sounds/{id}/stop", s.withSound(s.handleStop))
+	s.mux.HandleFunc("POST /sounds/{id}/pause", s.withSound(s.handlePause))
+	s.mux.HandleFunc("POST /sounds/{id}/resume", s.withSound(s.handleResume))
+	s.mux.HandleFunc("POST /sounds/{id}/seek", s.withSound(s.handleSeek))
+	s.mux.HandleFunc("POST /sounds/{id}/volume", s.withSound(s.handleVolume))
+	s.mux.HandleFunc("POST /stop-all", s.handleStopAll)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP реализует http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe принимает запросы по TCP, например на "127.0.0.1:8765".
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// ListenUnix принимает запросы через Unix-сокет path.
+// Оставшийся от прошлого запуска файл сокета удаляется.
+func (s *Server) ListenUnix(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	return s.Serve(l)
+}
+
+// Serve принимает запросы из готового слушателя.
+func (s *Server) Serve(l net.Listener) error {
+	return http.Serve(l, s)
+}
+
+// Subscribe подписывает на события жизненного цикла. Возвращает канал и функцию отписки.
+func (s *Server) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventsBuffer)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, ch)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// emit рассылает событие подписчикам. Вызывается под s.mu.
+func (s *Server) emit(typ string, snd *sound) {
+	ev := Event{Type: typ, ID: snd.id, Source: snd.source, Time: time.Now()}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Play запускает звук и возвращает его идентификатор. Если библиотека
+// перезапустила уже играющий экземпляр (RetriggerRestart), возвращается
+// его прежний идентификатор.
+func (s *Server) Play(source string, params playsound.PlayParams) (int, error) {
+	done, err := playsound.PlaySoundWithParams(source, params)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snd, created := s.register(done, source)
+	if created {
+		s.emit(EventStarted, snd)
+	}
+	return snd.id, nil
+}
+
+// register возвращает звук с каналом done. Звуку, который встретился впервые,
+// выдаётся новый идентификатор и запускается наблюдение за его окончанием.
+// Вызывается под s.mu.
+func (s *Server) register(done chan struct{}, source string) (*sound, bool) {
+	if id, ok := s.ids[done]; ok {
+		return s.sounds[id], false
+	}
+	s.nextID++
+	snd := &sound{id: s.nextID, source: source, done: done}
+	s.sounds[snd.id] = snd
+	s.ids[done] = snd.id
+	go s.watch(snd)
+	return snd, true
+}
+
+// registerActive выдаёт идентификаторы всем активным звукам библиотеки
+// и возвращает их снимки состояния вместе со звуками.
+func (s *Server) registerActive() ([]*sound, []playsound.SoundInfo) {
+	active := playsound.ListActive()
+	sounds := make([]*sound, len(active))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, info := range active {
+		sounds[i], _ = s.register(info.Done, info.Source)
+	}
+	return sounds, active
+}
+
+// watch ждёт окончания звука и удаляет его из реестра.
+func (s *Server) watch(snd *sound) {
+	<-snd.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sounds, snd.id)
+	delete(s.ids, snd.done)
+	if snd.stopped {
+		s.emit(EventStopped, snd)
+	} else {
+		s.emit(EventEnded, snd)
+	}
+}
+
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	var req PlayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+	if req.Source == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("source is required"))
+		return
+	}
+
+	id, err := s.Play(req.Source, req.params())
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	sounds, active := s.registerActive()
+	list := make([]SoundInfo, len(active))
+	for i, info := range active {
+		list[i] = soundInfo(sounds[i].id, info)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeJSON(w, http.StatusOK, list)
+}
+
+// withSound находит звук по идентификатору из пути и передаёт его обработчику.
+func (s *Server) withSound(h func(http.ResponseWriter, *http.Request, *sound)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid sound id %q", r.PathValue("id")))
+			return
+		}
+
+		s.mu.Lock()
+		snd, ok := s.sounds[id]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("sound %d not found", id))
+			return
+		}
+		h(w, r, snd)
+	}
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request, snd *sound) {
+	info, err := playsound.GetInfo(snd.done)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, soundInfo(snd.id, info))
+}
+
+func (s *Server) handlePosition(w http.ResponseWriter, r *http.Request, snd *sound) {
+	pos, err := playsound.GetPosition(snd.done)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	dur, _ := playsound.GetDuration(snd.done)
+	writeJSON(w, http.StatusOK, map[string]float64{"position": pos, "duration": dur})
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request, snd *sound) {
+	opts, err := transitionOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	fade, ok := stopFade(opts)
+	if !ok {
+		info, err := playsound.GetInfo(snd.done)
+		if err != nil {
+			writeError(w, controlStatus(err), err)
+			return
+		}
+		if info.Params.FadeOut {
+			fade = info.Params.FadeOutTime
+		}
+	}
+
+	// Блокировка держится и во время вызова: иначе звук, остановленный
+	// без затухания, мог бы закончиться и попасть в события как доигравший
+	// раньше, чем будет отмечен остановленным.
+	s.mu.Lock()
+	err = playsound.StopWithOptions(snd.done, fade, false)
+	if err == nil {
+		snd.stopped = true
+	}
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, controlStatus(err), err)
+		return
+	}
+	if opts.Wait {
+		<-snd.done
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleStopAll(w http.ResponseWriter, r *http.Request) {
+	opts, err := transitionOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Звуки, запущенные в обход сервера, тоже получают идентификаторы,
+	// чтобы подписчики узнали об их остановке
+	s.registerActive()
+	s.mu.Lock()
+	var dones []chan struct{}
+	for _, snd := range s.sounds {
+		snd.stopped = true
+		dones = append(dones, snd.done)
+	}
+	s.mu.Unlock()
+
+	if fade, ok := stopFade(opts); ok {
+		playsound.StopAllWithOptions(fade, false)
+	} else {
+		playsound.StopAll()
+	}
+	if opts.Wait {
+		for _, done := range dones {
+			<-done
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stopFade возвращает затухание остановки, если его задал клиент;
+// иначе звук останавливается по своим параметрам (FadeOut).
+func stopFade(opts playsound.TransitionOptions) (time.Duration, bool) {
+	if opts.NoFade {
+		return 0, true
+	}
+	return opts.Fade, opts.Fade > 0
+}
+
+// transitionOptions читает необязательные параметры плавности паузы, продолжения и остановки.
+// Пустое тело означает плавность из параметров звука.
+func transitionOptions(r *http.Request) (playsound.TransitionOptions, error) {
+	var req struct {
+		FadeMS int  `json:"fade_ms"`
+		NoFade bool `json:"no_fade"`
+		Wait   bool `json:"wait"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return playsound.TransitionOptions{}, fmt.Errorf("invalid request: %v", err)
+	}
+	if req.FadeMS < 0 {
+		return playsound.TransitionOptions{}, fmt.Errorf("invalid fade_ms %d", req.FadeMS)
+	}
+	return playsound.TransitionOptions{
+		Fade:   time.Duration(req.FadeMS) * time.Millisecond,
+		NoFade: req.NoFade,
+		Wait:   req.Wait,
+	}, nil
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request, snd *sound) {
+	opts, err := transitionOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.OnDone = s.emitOnDone(EventPaused, snd)
+	if err := playsound.PauseWithOptions(snd.done, opts); err != nil {
+		writeError(w, controlStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request, snd *sound) {
+	opts, err := transitionOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.OnDone = s.emitOnDone(EventResumed, snd)
+	if err := playsound.PlayOnWithOptions(snd.done, opts); err != nil {
+		writeError(w, controlStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emitOnDone возвращает обработчик окончания перехода, который рассылает
+// событие typ, только если звук действительно дошёл до нового состояния:
+// при плавной паузе — после затухания, а развёрнутый переход события не даёт.
+func (s *Server) emitOnDone(typ string, snd *sound) func(ok bool) {
+	return func(ok bool) {
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.emit(typ, snd)
+	}
+}
+
+func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request, snd *sound) {
+	var req struct {
+		Position *float64 `json:"position"`
+		Relative bool     `json:"relative"`
+		FadeMS   int      `json:"fade_ms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Position == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("position is required"))
+		return
+	}
+	if req.FadeMS < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid fade_ms %d", req.FadeMS))
+		return
+	}
+	pos, err := playsound.SeekWithOptions(snd.done, *req.Position, playsound.SeekOptions{
+		Relative: req.Relative,
+		Fade:     time.Duration(req.FadeMS) * time.Millisecond,
+	})
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]float64{"position": pos})
+}
+
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request, snd *sound) {
+	var req struct {
+		Volume *float64 `json:"volume"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Volume == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("volume is required"))
+		return
+	}
+	if *req.Volume < 0 || *req.Volume > 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("volume %v is out of range [0, 1]", *req.Volume))
+		return
+	}
+	if err := playsound.SetVolume(snd.done, *req.Volume); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents отдаёт события как server-sent events, пока клиент не отключится.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// controlStatus выбирает код ответа для ошибки управления звуком:
+// недопустимый переход (например, пауза уже приостановленного звука) — 409.
+func controlStatus(err error) int {
+	var terr *playsound.TransitionError
+	if errors.As(err, &terr) {
+		return http.StatusConflict
+	}
+	return http.StatusNotFound
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
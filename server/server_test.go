@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Roman77St/playsound"
+)
+
+// writeTestWAV записывает стерео-синус заданной длительности в WAV-файл.
+func writeTestWAV(t *testing.T, seconds float64) string {
+	t.Helper()
+	const rate = 44100
+	frames := int(rate * seconds)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+frames*4))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(2), uint32(rate), uint32(rate * 4), uint16(4), uint16(16)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(frames*4))
+	for i := 0; i < frames; i++ {
+		v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/rate))
+		binary.Write(&b, binary.LittleEndian, [2]int16{v, v})
+	}
+
+	path := t.TempDir() + "/test.wav"
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func do(t *testing.T, client *http.Client, method, url, body string) (*http.Response, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out
+}
+
+// nextEvent читает следующее событие из потока SSE.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Таймаут: событие не пришло")
+		return Event{}
+	}
+}
+
+func TestServerLifecycle(t *testing.T) {
+	srv := New()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// Подписываемся на события через SSE
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	events := make(chan Event, 16)
+	go func() {
+		scanner := bufio.NewScanner(stream.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var ev Event
+				json.Unmarshal([]byte(data), &ev)
+				events <- ev
+			}
+		}
+	}()
+
+	path := writeTestWAV(t, 5)
+	resp, body := do(t, ts.Client(), http.MethodPost, ts.URL+"/sounds", `{"source": "`+path+`", "volume": 0.5}`)
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", body["error"])
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("play: status %d, %v", resp.StatusCode, body)
+	}
+	id := int(body["id"].(float64))
+	soundURL := ts.URL + "/sounds/" + strconv.Itoa(id)
+
+	if ev := nextEvent(t, events); ev.Type != EventStarted || ev.ID != id {
+		t.Errorf("event = %+v; want started", ev)
+	}
+
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/volume", `{"volume": 0.3}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("volume: status %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/volume", `{"volume": 3}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid volume: status %d", resp.StatusCode)
+	}
+	if resp, out := do(t, ts.Client(), http.MethodPost, soundURL+"/seek", `{"position": 2}`); resp.StatusCode != http.StatusOK || out["position"] != 2.0 {
+		t.Errorf("seek: status %d, body %v", resp.StatusCode, out)
+	}
+	if resp, out := do(t, ts.Client(), http.MethodPost, soundURL+"/seek", `{"position": 60, "relative": true}`); resp.StatusCode != http.StatusOK || out["position"].(float64) > 5.01 {
+		t.Errorf("seek past the end: status %d, body %v; want clamped to duration", resp.StatusCode, out)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/seek", `{"position": 2}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("seek: status %d", resp.StatusCode)
+	}
+
+	_, info := do(t, ts.Client(), http.MethodGet, soundURL, "")
+	if info["volume"] != 0.3 || info["duration"].(float64) < 4.9 || info["position"].(float64) < 1.5 {
+		t.Errorf("info = %v", info)
+	}
+
+	// Пауза, затухание которой развернули, события paused не даёт
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/pause", `{"fade_ms": 300}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("pause: status %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/resume", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("resume during fade: status %d", resp.StatusCode)
+	}
+	if ev := nextEvent(t, events); ev.Type != EventResumed {
+		t.Errorf("event = %+v; want resumed without paused", ev)
+	}
+
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/pause", `{"fade_ms": 50, "wait": true}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("pause: status %d", resp.StatusCode)
+	}
+	if ev := nextEvent(t, events); ev.Type != EventPaused {
+		t.Errorf("event = %+v; want paused", ev)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/pause", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("second pause: status %d; want 409", resp.StatusCode)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/resume", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("resume: status %d", resp.StatusCode)
+	}
+	if ev := nextEvent(t, events); ev.Type != EventResumed {
+		t.Errorf("event = %+v; want resumed", ev)
+	}
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/pause", `{"fade_ms": -1}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("pause with bad fade: status %d; want 400", resp.StatusCode)
+	}
+
+	list, err := ts.Client().Get(ts.URL + "/sounds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sounds []SoundInfo
+	json.NewDecoder(list.Body).Decode(&sounds)
+	list.Body.Close()
+	if len(sounds) != 1 || sounds[0].ID != id {
+		t.Errorf("list = %+v", sounds)
+	}
+
+	if resp, _ := do(t, ts.Client(), http.MethodPost, soundURL+"/stop", `{"fade_ms": 50, "wait": true}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("stop: status %d", resp.StatusCode)
+	}
+	if ev := nextEvent(t, events); ev.Type != EventStopped || ev.ID != id {
+		t.Errorf("event = %+v; want stopped", ev)
+	}
+
+	if resp, _ := do(t, ts.Client(), http.MethodGet, soundURL, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stopped sound: status %d; want 404", resp.StatusCode)
+	}
+}
+
+// Сервер видит звуки, запущенные и приостановленные напрямую через библиотеку,
+// а перезапуск источника (RetriggerRestart) сохраняет идентификатор звука
+func TestServerLibrarySounds(t *testing.T) {
+	srv := New()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	events, unsubscribe := srv.Subscribe()
+	defer unsubscribe()
+
+	path := writeTestWAV(t, 5)
+	done, err := playsound.PlaySoundWithParams(path, playsound.PlayParams{Volume: 0.5})
+	if err != nil {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", err)
+	}
+	defer playsound.StopAllWithOptions(0, true)
+	if err := playsound.PauseWithOptions(done, playsound.TransitionOptions{NoFade: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ts.Client().Get(ts.URL + "/sounds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sounds []SoundInfo
+	json.NewDecoder(list.Body).Decode(&sounds)
+	list.Body.Close()
+	if len(sounds) != 1 || !sounds[0].Paused || sounds[0].State != "paused" || sounds[0].Source != path {
+		t.Fatalf("list = %+v; want one paused sound", sounds)
+	}
+	id := sounds[0].ID
+
+	playsound.SetSourceLimit(path, playsound.SourceLimit{MaxInstances: 1, Policy: playsound.RetriggerRestart})
+	defer playsound.SetSourceLimit(path, playsound.SourceLimit{})
+	for i := 0; i < 2; i++ {
+		resp, body := do(t, ts.Client(), http.MethodPost, ts.URL+"/sounds", `{"source": "`+path+`"}`)
+		if resp.StatusCode != http.StatusCreated || int(body["id"].(float64)) != id {
+			t.Errorf("restart: status %d, body %v; want id %d", resp.StatusCode, body, id)
+		}
+	}
+
+	if resp, _ := do(t, ts.Client(), http.MethodPost, ts.URL+"/stop-all", `{"no_fade": true, "wait": true}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("stop-all: status %d", resp.StatusCode)
+	}
+	if ev := nextEvent(t, events); ev.Type != EventStopped || ev.ID != id {
+		t.Errorf("event = %+v; want stopped %d", ev, id)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	ts := httptest.NewServer(New())
+	defer ts.Close()
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/sounds", `{`, http.StatusBadRequest},
+		{http.MethodPost, "/sounds", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/sounds", `{"source": "/no/such/file.wav"}`, http.StatusUnprocessableEntity},
+		{http.MethodGet, "/sounds/abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/sounds/42", "", http.StatusNotFound},
+		{http.MethodPost, "/sounds/42/pause", "", http.StatusNotFound},
+		{http.MethodPost, "/stop-all", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if resp, _ := do(t, ts.Client(), tt.method, ts.URL+tt.path, tt.body); resp.StatusCode != tt.want {
+			t.Errorf("%s %s: status %d; want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	path := t.TempDir() + "/playsound.sock"
+	srv := New()
+	go srv.ListenUnix(path)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://unix/sounds"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d; want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,258 @@
+package playsound
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// speedOfSound — скорость звука в единицах мира в секунду (метры) для эффекта Доплера.
+const speedOfSound = 343.3
+
+// Vec3 — вектор или точка в трёхмерном пространстве.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (v Vec3) Add(o Vec3) Vec3      { return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+func (v Vec3) Sub(o Vec3) Vec3      { return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+func (v Vec3) Scale(k float64) Vec3 { return Vec3{v.X * k, v.Y * k, v.Z * k} }
+func (v Vec3) Dot(o Vec3) float64   { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+func (v Vec3) Len() float64         { return math.Sqrt(v.Dot(v)) }
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+// Norm возвращает вектор единичной длины (нулевой вектор остаётся нулевым).
+func (v Vec3) Norm() Vec3 {
+	if l := v.Len(); l > 0 {
+		return v.Scale(1 / l)
+	}
+	return v
+}
+
+// DistanceModel определяет, как громкость падает с расстоянием (модели как в OpenAL).
+type DistanceModel int
+
+const (
+	InverseDistance     DistanceModel = iota // ref / (ref + rolloff * (d - ref))
+	LinearDistance                           // 1 - rolloff * (d - ref) / (max - ref)
+	ExponentialDistance                      // (d / ref) ^ -rolloff
+)
+
+// Listener описывает положение и ориентацию слушателя.
+type Listener struct {
+	Position Vec3
+	Velocity Vec3 // Единиц в секунду, используется для эффекта Доплера
+	Forward  Vec3 // Направление взгляда
+	Up       Vec3 // Направление «вверх»
+}
+
+// SpatialParams описывает источник звука в пространстве.
+type SpatialParams struct {
+	Position    Vec3
+	Velocity    Vec3          // Единиц в секунду, используется для эффекта Доплера
+	Model       DistanceModel // Модель затухания с расстоянием
+	RefDistance float64       // Расстояние, на котором громкость не ослабляется (0 — 1)
+	MaxDistance float64       // Дальше этого расстояния громкость не падает (0 — без ограничения)
+	Rolloff     float64       // Скорость затухания (0 — 1)
+	Doppler     bool          // Менять высоту тона в зависимости от скоростей
+}
+
+var listener = struct {
+	sync.Mutex
+	Listener
+}{Listener: Listener{Forward: Vec3{0, 0, -1}, Up: Vec3{0, 1, 0}}}
+
+// SetListener задаёт положение, скорость и ориентацию слушателя для всех 3D-звуков.
+func SetListener(l Listener) {
+	if l.Forward.Len() == 0 {
+		l.Forward = Vec3{0, 0, -1}
+	}
+	if l.Up.Len() == 0 {
+		l.Up = Vec3{0, 1, 0}
+	}
+	listener.Lock()
+	defer listener.Unlock()
+	listener.Listener = l
+}
+
+// GetListener возвращает текущие параметры слушателя.
+func GetListener() Listener {
+	listener.Lock()
+	defer listener.Unlock()
+	return listener.Listener
+}
+
+// withDefaults подставляет значения по умолчанию для незаданных параметров.
+func (sp SpatialParams) withDefaults() SpatialParams {
+	if sp.RefDistance <= 0 {
+		sp.RefDistance = 1
+	}
+	if sp.Rolloff <= 0 {
+		sp.Rolloff = 1
+	}
+	return sp
+}
+
+// distanceGain рассчитывает ослабление громкости на расстоянии d.
+func (sp SpatialParams) distanceGain(d float64) float64 {
+	ref := sp.RefDistance
+	d = math.Max(d, ref)
+	if sp.MaxDistance > ref {
+		d = math.Min(d, sp.MaxDistance)
+	}
+
+	switch sp.Model {
+	case LinearDistance:
+		if sp.MaxDistance <= ref {
+			return 1
+		}
+		return clamp01(1 - sp.Rolloff*(d-ref)/(sp.MaxDistance-ref))
+	case ExponentialDistance:
+		return math.Pow(d/ref, -sp.Rolloff)
+	default:
+		return ref / (ref + sp.Rolloff*(d-ref))
+	}
+}
+
+// spatialize рассчитывает панораму, ослабление и множитель частоты Доплера для источника.
+func (sp SpatialParams) spatialize(l Listener) (pan, gain, doppler float64) {
+	toSource := sp.Position.Sub(l.Position)
+	dist := toSource.Len()
+
+	right := l.Forward.Cross(l.Up).Norm()
+	if dist > 0 {
+		pan = math.Max(-1, math.Min(1, toSource.Scale(1/dist).Dot(right)))
+	}
+	gain = sp.distanceGain(dist)
+
+	doppler = 1
+	if sp.Doppler && dist > 0 {
+		dir := toSource.Scale(1 / dist) // От слушателя к источнику
+		// Скорости ограничены, чтобы не уйти за звуковой барьер
+		limit := speedOfSound * 0.9
+		vl := math.Max(-limit, math.Min(l.Velocity.Dot(dir), limit))
+		vs := math.Max(-limit, math.Min(sp.Velocity.Dot(dir), limit))
+		doppler = (speedOfSound + vl) / (speedOfSound + vs)
+	}
+	return pan, gain, doppler
+}
+
+// panGains переводит панораму -1..1 в коэффициенты каналов. Кривая равной мощности
+// ограничена единицей: в центре оба канала звучат без изменений, а при смещении
+// ослабляется только противоположный канал.
+func panGains(pan float64) (float64, float64) {
+	theta := (pan + 1) * math.Pi / 4
+	return math.Min(1, math.Sqrt2*math.Cos(theta)), math.Min(1, math.Sqrt2*math.Sin(theta))
+}
+
+// panner применяет к звуку панораму и пространственное положение.
+// Параметры пересчитываются на каждый буфер, а коэффициенты плавно
+// меняются внутри буфера, чтобы не было щелчков.
+type panner struct {
+	pan     float64
+	spatial *SpatialParams
+
+	started    bool
+	curL, curR float64
+}
+
+// active сообщает, нужна ли обработка панорамой. После сброса панорамы
+// обработка продолжается, пока коэффициенты плавно не вернутся к единице.
+func (p *panner) active() bool {
+	return p.pan != 0 || p.spatial != nil || (p.started && (p.curL != 1 || p.curR != 1))
+}
+
+// targets пересчитывает положение источника и возвращает множитель Доплера.
+func (p *panner) targets() (left, right, doppler float64) {
+	if p.spatial == nil {
+		left, right = panGains(p.pan)
+		return left, right, 1
+	}
+	pan, gain, doppler := p.spatial.spatialize(GetListener())
+	left, right = panGains(math.Max(-1, math.Min(1, pan+p.pan)))
+	return left * gain, right * gain, doppler
+}
+
+// process применяет коэффициенты каналов к буферу с линейным переходом от предыдущих.
+// Точечный 3D-источник сначала сводится в моно.
+func (p *panner) process(frames []float32, left, right float64) {
+	if !p.started {
+		p.curL, p.curR, p.started = left, right, true
+	}
+
+	n := len(frames) / 2
+	for i := 0; i < n; i++ {
+		t := float64(i+1) / float64(n)
+		gl := p.curL + (left-p.curL)*t
+		gr := p.curR + (right-p.curR)*t
+
+		l, r := frames[i*2], frames[i*2+1]
+		if p.spatial != nil {
+			m := (l + r) / 2
+			l, r = m, m
+		}
+		frames[i*2] = l * float32(gl)
+		frames[i*2+1] = r * float32(gr)
+	}
+	if n > 0 {
+		p.curL, p.curR = left, right
+	}
+}
+
+// SetPan смещает звук в стереопанораме: -1 — левый канал, 0 — центр, 1 — правый.
+// Для 3D-звука смещение добавляется к рассчитанному по положению.
+func SetPan(done chan struct{}, pan float64) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if pan < -1 || pan > 1 {
+		return fmt.Errorf("pan %v is out of range [-1, 1]", pan)
+	}
+
+	control.tracker.mu.Lock()
+	defer control.tracker.mu.Unlock()
+	control.tracker.space.pan = pan
+	return nil
+}
+
+// SetSpatial включает 3D-режим звука с заданными параметрами; nil отключает его.
+func SetSpatial(done chan struct{}, params *SpatialParams) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	control.tracker.mu.Lock()
+	defer control.tracker.mu.Unlock()
+	if params == nil {
+		control.tracker.space.spatial = nil
+		control.tracker.rate.doppler = 0
+		return nil
+	}
+	sp := params.withDefaults()
+	control.tracker.space.spatial = &sp
+	return nil
+}
+
+// SetSourcePosition обновляет положение и скорость 3D-звука.
+func SetSourcePosition(done chan struct{}, position, velocity Vec3) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	control.tracker.mu.Lock()
+	defer control.tracker.mu.Unlock()
+	if control.tracker.space.spatial == nil {
+		return fmt.Errorf("sound is not spatial")
+	}
+	// Копируем параметры, чтобы не менять структуру, переданную пользователем
+	sp := *control.tracker.space.spatial
+	sp.Position, sp.Velocity = position, velocity
+	control.tracker.space.spatial = &sp
+	return nil
+}
@@ -0,0 +1,111 @@
+package playsound
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// channelPeaks прогоняет поток через trackingStream и возвращает пики левого и правого каналов.
+func channelPeaks(t *testing.T, ts *trackingStream) (float64, float64) {
+	t.Helper()
+	buf := make([]float32, 1024)
+	var left, right float64
+	for {
+		n, err := ts.readProcessed(buf)
+		for i := 0; i < n; i++ {
+			left = math.Max(left, math.Abs(float64(buf[i*2])))
+			right = math.Max(right, math.Abs(float64(buf[i*2+1])))
+		}
+		if err != nil || n == 0 {
+			return left, right
+		}
+	}
+}
+
+// Тест панорамы: центр не меняет уровень, крайние положения глушат противоположный канал
+func TestPan(t *testing.T) {
+	tests := []struct {
+		pan         float64
+		left, right float64
+	}{
+		{0, 1, 1},
+		{-1, 1, 0},
+		{1, 0, 1},
+		{0.5, math.Sqrt2 * math.Cos(3*math.Pi/8), 1},
+	}
+
+	for _, tt := range tests {
+		l, r := panGains(tt.pan)
+		if math.Abs(l-tt.left) > 1e-9 || math.Abs(r-tt.right) > 1e-9 {
+			t.Errorf("panGains(%v) = %.3f, %.3f; want %.3f, %.3f", tt.pan, l, r, tt.left, tt.right)
+		}
+	}
+
+	const rate = 8000
+	pcm := sinePCM(440, rate, 0.5)
+	ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), rate}}
+	ts.space.pan = -1
+	left, right := channelPeaks(t, ts)
+	if left < 0.45 || right > 1e-3 {
+		t.Errorf("hard left pan: peaks = %.3f, %.3f; want ~0.5, 0", left, right)
+	}
+}
+
+// Тест моделей затухания с расстоянием
+func TestDistanceModels(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SpatialParams
+		dist   float64
+		want   float64
+	}{
+		{"Inverse at ref", SpatialParams{Model: InverseDistance}, 1, 1},
+		{"Inverse", SpatialParams{Model: InverseDistance}, 4, 0.25},
+		{"Inside ref", SpatialParams{Model: InverseDistance, RefDistance: 2}, 1, 1},
+		{"Linear", SpatialParams{Model: LinearDistance, MaxDistance: 11}, 6, 0.5},
+		{"Linear beyond max", SpatialParams{Model: LinearDistance, MaxDistance: 11}, 50, 0},
+		{"Exponential", SpatialParams{Model: ExponentialDistance, Rolloff: 2}, 2, 0.25},
+		{"Clamped by max", SpatialParams{Model: InverseDistance, MaxDistance: 4}, 100, 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.withDefaults().distanceGain(tt.dist); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("distanceGain(%v) = %v; want %v", tt.dist, got, tt.want)
+			}
+		})
+	}
+}
+
+// Тест 3D-положения: источник справа звучит справа, приближающийся — выше тоном
+func TestSpatialize(t *testing.T) {
+	l := Listener{Forward: Vec3{0, 0, -1}, Up: Vec3{0, 1, 0}}
+
+	sp := SpatialParams{Position: Vec3{2, 0, 0}}.withDefaults()
+	pan, gain, doppler := sp.spatialize(l)
+	if math.Abs(pan-1) > 1e-9 || math.Abs(gain-0.5) > 1e-9 || doppler != 1 {
+		t.Errorf("source on the right: pan=%v gain=%v doppler=%v; want 1, 0.5, 1", pan, gain, doppler)
+	}
+
+	sp = SpatialParams{Position: Vec3{0, 0, -10}, Velocity: Vec3{0, 0, 34.33}, Doppler: true}.withDefaults()
+	_, _, doppler = sp.spatialize(l)
+	if want := 1 / 0.9; math.Abs(doppler-want) > 1e-9 {
+		t.Errorf("approaching source: doppler = %v; want %v", doppler, want)
+	}
+
+	sp.Velocity = Vec3{0, 0, -34.33}
+	if _, _, doppler = sp.spatialize(l); doppler >= 1 {
+		t.Errorf("receding source: doppler = %v; want < 1", doppler)
+	}
+
+	// Источник слева от слушателя попадает в левый канал
+	const rate = 8000
+	pcm := sinePCM(440, rate, 0.5)
+	ts := &trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), rate}}
+	ts.space.spatial = &SpatialParams{Position: Vec3{-1, 0, 0}, RefDistance: 1, Rolloff: 1}
+	left, right := channelPeaks(t, ts)
+	if left < 0.45 || right > 1e-3 {
+		t.Errorf("source on the left: peaks = %.3f, %.3f; want ~0.5, 0", left, right)
+	}
+}
@@ -0,0 +1,462 @@
+package playsound
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	minSpeed = 0.25 // Минимальная скорость воспроизведения
+	maxSpeed = 4.0  // Максимальная скорость воспроизведения
+	maxPitch = 24.0 // Максимальный сдвиг высоты тона в полутонах (в обе стороны)
+
+	// Параметры WSOLA: длина зерна, шаг вывода и окно поиска наилучшего совпадения (в кадрах).
+	stretchGrain     = 1024
+	stretchHop       = stretchGrain / 2
+	stretchTolerance = 256
+
+	// resampleChunk — сколько кадров ресемплер запрашивает у источника за раз.
+	resampleChunk = 512
+)
+
+// rateStage меняет скорость и высоту тона потока.
+// Скорость раскладывается на растяжение во времени (tempo, тон сохраняется)
+// и ресемплинг (ratio, тон меняется вместе со скоростью): speed = tempo * ratio.
+type rateStage struct {
+	speed         float64 // Скорость воспроизведения, 1 — обычная
+	pitch         float64 // Сдвиг тона в полутонах
+	preservePitch bool    // Сохранять тон при изменении скорости
+	srcRatio      float64 // Отношение частоты файла к частоте микшера (0 — совпадают)
+	doppler       float64 // Множитель частоты от эффекта Доплера (0 — без эффекта)
+
+	stretch    stretcher
+	resample   resampler
+	stretching bool // Растяжение включено в цепочку или ещё отдаёт накопленные кадры
+	resampling bool // То же для ресемплера
+}
+
+// factors возвращает коэффициенты растяжения и ресемплинга для текущих настроек.
+// Ресемплинг заодно приводит частоту файла к частоте микшера.
+func (rs *rateStage) factors() (tempo, ratio float64) {
+	speed := rs.playbackSpeed()
+	src := rs.srcRatio
+	if src <= 0 {
+		src = 1
+	}
+	ratio = math.Pow(2, rs.pitch/12) * src
+	if !rs.preservePitch {
+		ratio *= speed
+	}
+	tempo = speed * src / ratio
+	// Доплер сдвигает тон и скорость вместе, как при изменении скорости без сохранения тона
+	if rs.doppler > 0 {
+		ratio *= rs.doppler
+	}
+	return tempo, ratio
+}
+
+// playbackSpeed возвращает скорость воспроизведения относительно исходного трека.
+func (rs *rateStage) playbackSpeed() float64 {
+	if rs.speed <= 0 {
+		return 1
+	}
+	return rs.speed
+}
+
+// active сообщает, нужна ли обработка, или данные можно передавать плееру как есть.
+func (rs *rateStage) active() bool {
+	tempo, ratio := rs.factors()
+	return !nearOne(tempo) || !nearOne(ratio) || rs.stretching || rs.resampling
+}
+
+// read заполняет dst, забирая данные из src через растяжение и ресемплинг.
+// Ступень, которая стала не нужна, сначала отдаёт накопленные кадры и только
+// потом выходит из цепочки: иначе они пропали бы, а при следующем включении
+// прозвучали бы не на своём месте. Начатый слив доводится до конца, даже
+// если ступень снова понадобилась.
+func (rs *rateStage) read(dst []float32, src frameReader) (int, error) {
+	tempo, ratio := rs.factors()
+	rs.stretch.tempo = tempo
+	rs.resample.ratio = ratio
+
+	if !nearOne(tempo) || rs.stretching {
+		next := src
+		src = func(buf []float32) (int, error) {
+			if nearOne(rs.stretch.tempo) || rs.stretch.draining {
+				return drainStage(buf, next, rs.stretch.drain, &rs.stretching)
+			}
+			rs.stretching = true
+			return rs.stretch.read(buf, next)
+		}
+	}
+	if nearOne(ratio) || rs.resample.draining {
+		if rs.resampling {
+			return drainStage(dst, src, rs.resample.drain, &rs.resampling)
+		}
+		return src(dst)
+	}
+	rs.resampling = true
+	return rs.resample.read(dst, src)
+}
+
+// drainStage выдаёт накопленные ступенью кадры, а когда они кончаются,
+// снимает флаг on и дочитывает dst из src напрямую.
+func drainStage(dst []float32, src frameReader, drain func([]float32, frameReader) (int, bool), on *bool) (int, error) {
+	n, done := drain(dst, src)
+	if !done {
+		return n, nil
+	}
+	*on = false
+	if n == len(dst)/2 {
+		return n, nil
+	}
+	m, err := src(dst[n*2:])
+	return n + m, err
+}
+
+// reset сбрасывает внутренние буферы, например после перемотки.
+func (rs *rateStage) reset() {
+	rs.stretch.reset()
+	rs.resample.reset()
+	rs.stretching, rs.resampling = false, false
+}
+
+func nearOne(v float64) bool {
+	return math.Abs(v-1) < 1e-6
+}
+
+// resampler меняет скорость потока линейной интерполяцией между соседними кадрами.
+type resampler struct {
+	ratio float64
+
+	frac      float64
+	cur, next [2]float32
+	primed    bool
+	done      bool
+	srcErr    error
+	in        []float32
+	inPos     int
+	inLen     int
+	rest      []float32 // Кадры, которые осталось выдать при выходе из цепочки
+	draining  bool
+}
+
+// nextFrame берёт следующий кадр из внутреннего буфера, пополняя его из src.
+func (r *resampler) nextFrame(src frameReader) ([2]float32, bool) {
+	if r.inPos >= r.inLen {
+		if r.srcErr != nil {
+			return [2]float32{}, false
+		}
+		if r.in == nil {
+			r.in = make([]float32, resampleChunk*2)
+		}
+		n, err := src(r.in)
+		r.inPos, r.inLen = 0, n
+		r.srcErr = err
+		if n == 0 {
+			if r.srcErr == nil {
+				r.srcErr = io.EOF
+			}
+			return [2]float32{}, false
+		}
+	}
+	f := [2]float32{r.in[r.inPos*2], r.in[r.inPos*2+1]}
+	r.inPos++
+	return f, true
+}
+
+func (r *resampler) read(dst []float32, src frameReader) (int, error) {
+	if !r.primed {
+		var ok bool
+		if r.cur, ok = r.nextFrame(src); !ok {
+			return 0, r.srcErr
+		}
+		if r.next, ok = r.nextFrame(src); !ok {
+			r.next = r.cur
+			r.done = true
+		}
+		r.primed = true
+	}
+
+	frames := len(dst) / 2
+	i := 0
+	for ; i < frames && !r.done; i++ {
+		f := float32(r.frac)
+		dst[i*2] = r.cur[0] + (r.next[0]-r.cur[0])*f
+		dst[i*2+1] = r.cur[1] + (r.next[1]-r.cur[1])*f
+
+		r.frac += r.ratio
+		for r.frac >= 1 {
+			r.frac--
+			r.cur = r.next
+			next, ok := r.nextFrame(src)
+			if !ok {
+				r.done = true
+				break
+			}
+			r.next = next
+		}
+	}
+
+	if i == 0 && r.done {
+		return 0, r.srcErr
+	}
+	return i, nil
+}
+
+func (r *resampler) reset() {
+	*r = resampler{ratio: r.ratio, in: r.in, rest: r.rest[:0]}
+}
+
+// drain выдаёт без ресемплинга кадры, которые уже забраны из источника:
+// ближайший к текущей дробной позиции и всё, что лежит во входном буфере.
+// done — буфер опустел и ресемплер сброшен.
+func (r *resampler) drain(dst []float32, _ frameReader) (int, bool) {
+	if !r.draining {
+		rest := r.rest[:0]
+		if r.primed && !r.done {
+			if r.frac < 0.5 {
+				rest = append(rest, r.cur[0], r.cur[1])
+			}
+			rest = append(rest, r.next[0], r.next[1])
+			rest = append(rest, r.in[r.inPos*2:r.inLen*2]...)
+		}
+		r.rest, r.draining = rest, true
+	}
+
+	n := copy(dst, r.rest) / 2
+	r.rest = append(r.rest[:0], r.rest[n*2:]...)
+	if len(r.rest) > 0 {
+		return n, false
+	}
+	r.reset()
+	return n, true
+}
+
+// stretcher меняет темп без изменения тона методом WSOLA:
+// зёрна входного сигнала с окном Ханна накладываются с фиксированным шагом вывода,
+// а начало каждого зерна подбирается по максимуму корреляции с продолжением предыдущего.
+type stretcher struct {
+	tempo float64
+
+	window    []float32
+	in        []float32 // Буфер входных кадров
+	chunk     []float32 // Временный буфер для чтения из источника
+	inBase    int64     // Абсолютный номер первого кадра в in
+	srcDone   bool
+	srcErr    error
+	anaPos    float64 // Номинальная позиция следующего зерна во входном потоке
+	prevStart int64   // Начало предыдущего зерна
+	started   bool
+	acc       []float32 // Накопитель overlap-add длиной в одно зерно
+	out       []float32 // Готовые к выдаче кадры
+	outPos    int
+	finished  bool
+	draining  bool
+}
+
+func (s *stretcher) reset() {
+	*s = stretcher{tempo: s.tempo, window: s.window, chunk: s.chunk}
+}
+
+// inEnd возвращает абсолютный номер кадра, следующего за последним буферизованным.
+func (s *stretcher) inEnd() int64 {
+	return s.inBase + int64(len(s.in)/2)
+}
+
+// fill дочитывает вход, пока в буфере нет кадра с номером end или пока источник не иссяк.
+func (s *stretcher) fill(end int64, src frameReader) {
+	if s.chunk == nil {
+		s.chunk = make([]float32, resampleChunk*2)
+	}
+	for !s.srcDone && s.inEnd() < end {
+		n, err := src(s.chunk)
+		s.in = append(s.in, s.chunk[:n*2]...)
+		if err != nil {
+			s.srcDone = true
+			if err != io.EOF {
+				s.srcErr = err
+			}
+		} else if n == 0 {
+			s.srcDone = true
+		}
+	}
+}
+
+// frame возвращает кадр с абсолютным номером pos или тишину за пределами буфера.
+func (s *stretcher) frame(pos int64) (float32, float32) {
+	i := pos - s.inBase
+	if i < 0 || i >= int64(len(s.in)/2) {
+		return 0, 0
+	}
+	return s.in[i*2], s.in[i*2+1]
+}
+
+// bestStart ищет начало зерна рядом с nominal, лучше всего продолжающее предыдущее.
+func (s *stretcher) bestStart(nominal int64) int64 {
+	if !s.started {
+		return nominal
+	}
+	target := s.prevStart + stretchHop
+	best, bestCorr := nominal, math.Inf(-1)
+	for delta := int64(-stretchTolerance); delta <= stretchTolerance; delta += 4 {
+		start := nominal + delta
+		if start < s.inBase {
+			continue
+		}
+		var corr float64
+		for n := int64(0); n < stretchHop; n += 2 {
+			al, ar := s.frame(start + n)
+			bl, br := s.frame(target + n)
+			corr += float64((al + ar) * (bl + br))
+		}
+		if corr > bestCorr {
+			best, bestCorr = start, corr
+		}
+	}
+	return best
+}
+
+// grain добавляет очередное зерно в накопитель и выдаёт stretchHop готовых кадров.
+func (s *stretcher) grain(src frameReader) bool {
+	if s.window == nil {
+		s.window = make([]float32, stretchGrain)
+		for n := range s.window {
+			s.window[n] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(n)/stretchGrain))
+		}
+	}
+	if s.acc == nil {
+		s.acc = make([]float32, stretchGrain*2)
+	}
+
+	nominal := int64(s.anaPos)
+	s.fill(nominal+stretchTolerance+stretchGrain, src)
+	if s.srcDone && nominal >= s.inEnd() {
+		return false
+	}
+
+	start := s.bestStart(nominal)
+	for n := 0; n < stretchGrain; n++ {
+		l, r := s.frame(start + int64(n))
+		w := s.window[n]
+		// Первое зерно не с чем накладывать: его передняя половина идёт без окна,
+		// чтобы звук не нарастал из тишины при включении растяжения
+		if !s.started && n < stretchHop {
+			w = 1
+		}
+		s.acc[n*2] += l * w
+		s.acc[n*2+1] += r * w
+	}
+
+	s.out = append(s.out[:0], s.acc[:stretchHop*2]...)
+	s.outPos = 0
+	copy(s.acc, s.acc[stretchHop*2:])
+	clear(s.acc[len(s.acc)-stretchHop*2:])
+
+	s.prevStart, s.started = start, true
+	s.anaPos += stretchHop * s.tempo
+
+	// Отбрасываем вход, который больше не понадобится ни поиску, ни следующему зерну.
+	keep := min(int64(s.anaPos)-stretchTolerance, s.prevStart+stretchHop)
+	if drop := keep - s.inBase; drop > 0 {
+		drop = min(drop, int64(len(s.in)/2))
+		s.in = append(s.in[:0], s.in[drop*2:]...)
+		s.inBase += drop
+	}
+	return true
+}
+
+// drain выдаёт без растяжения то, что уже забрано из источника: готовые кадры,
+// затухающую половину последнего зерна вперемешку с продолжением входа
+// и остаток входного буфера. После этого поток можно читать из источника
+// напрямую. done — всё выдано и растяжение сброшено.
+func (s *stretcher) drain(dst []float32, src frameReader) (int, bool) {
+	if !s.draining {
+		s.draining = true
+		out := append(s.out[:0], s.out[s.outPos*2:]...)
+		if s.started && !s.finished {
+			nominal := int64(s.anaPos)
+			s.fill(nominal+stretchTolerance+stretchHop, src)
+			start := s.bestStart(nominal)
+			for n := 0; n < stretchHop; n++ {
+				l, r := s.frame(start + int64(n))
+				out = append(out, s.acc[n*2]+l*s.window[n], s.acc[n*2+1]+r*s.window[n])
+			}
+			if from := start + stretchHop - s.inBase; from < int64(len(s.in)/2) {
+				out = append(out, s.in[from*2:]...)
+			}
+		} else if !s.started {
+			out = append(out, s.in...)
+		}
+		s.out, s.outPos = out, 0
+	}
+
+	n := copy(dst, s.out[s.outPos*2:]) / 2
+	s.outPos += n
+	if s.outPos < len(s.out)/2 {
+		return n, false
+	}
+	s.reset()
+	return n, true
+}
+
+func (s *stretcher) read(dst []float32, src frameReader) (int, error) {
+	frames := len(dst) / 2
+	i := 0
+	for i < frames {
+		if s.outPos >= len(s.out)/2 {
+			if s.finished || !s.grain(src) {
+				s.finished = true
+				break
+			}
+		}
+		n := copy(dst[i*2:], s.out[s.outPos*2:]) / 2
+		s.outPos += n
+		i += n
+	}
+
+	if i == 0 && s.finished {
+		if s.srcErr != nil {
+			return 0, s.srcErr
+		}
+		return 0, io.EOF
+	}
+	return i, nil
+}
+
+// SetSpeed меняет скорость воспроизведения звука (1 — обычная, 0.5 — вдвое медленнее).
+// Если при запуске был задан PreservePitch, тон сохраняется за счёт растяжения во времени.
+// GetPosition и Seek продолжают работать в секундах исходного трека.
+func SetSpeed(done chan struct{}, rate float64) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if rate < minSpeed || rate > maxSpeed {
+		return fmt.Errorf("speed %v is out of range [%v, %v]", rate, minSpeed, maxSpeed)
+	}
+
+	control.tracker.mu.Lock()
+	defer control.tracker.mu.Unlock()
+	control.tracker.rate.speed = rate
+	return nil
+}
+
+// SetPitch сдвигает высоту тона звука на заданное число полутонов без изменения скорости.
+func SetPitch(done chan struct{}, semitones float64) error {
+	control, ok := getControl(done)
+	if !ok {
+		return fmt.Errorf("sound not found")
+	}
+
+	if math.Abs(semitones) > maxPitch {
+		return fmt.Errorf("pitch %v is out of range [-%v, %v]", semitones, maxPitch, maxPitch)
+	}
+
+	control.tracker.mu.Lock()
+	defer control.tracker.mu.Unlock()
+	control.tracker.rate.pitch = semitones
+	return nil
+}
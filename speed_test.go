@@ -0,0 +1,70 @@
+package playsound
+
+import (
+	"io"
+	"testing"
+)
+
+// rampReader выдаёт кадры, значение которых равно их номеру в источнике.
+func rampReader(total int) frameReader {
+	pos := 0
+	return func(dst []float32) (int, error) {
+		n := min(len(dst)/2, total-pos)
+		for i := 0; i < n; i++ {
+			dst[i*2], dst[i*2+1] = float32(pos+i), float32(pos+i)
+		}
+		pos += n
+		if n == 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+// Тест переключения скорости 1 → 1.5 → 1 → 1.5 → 1: выключаемая ступень
+// отдаёт накопленные кадры, поэтому выход идёт по порядку источника без
+// пропусков и возвратов, а на обычной скорости кадры снова идут подряд
+func TestRateStageToggle(t *testing.T) {
+	tests := []struct {
+		name          string
+		preservePitch bool
+		maxStep       float32 // Наибольший шаг между соседними кадрами выхода
+	}{
+		{"resample", false, 2},
+		{"stretch", true, 4},
+	}
+	for _, tt := range tests {
+		rs := rateStage{preservePitch: tt.preservePitch}
+		src := rampReader(1 << 20)
+
+		var out []float32
+		buf := make([]float32, 700*2)
+		for _, speed := range []float64{1, 1.5, 1, 1.5, 1} {
+			rs.speed = speed
+			for read := 0; read < 4000; {
+				n, err := rs.read(buf, src)
+				if err != nil {
+					t.Fatalf("%s: read() error: %v", tt.name, err)
+				}
+				for i := 0; i < n; i++ {
+					out = append(out, buf[i*2])
+				}
+				read += n
+			}
+		}
+
+		for i := 1; i < len(out); i++ {
+			if step := out[i] - out[i-1]; step < -0.5 || step > tt.maxStep {
+				t.Fatalf("%s: frame %d: %v after %v; want source order", tt.name, i, out[i], out[i-1])
+			}
+		}
+		for i := len(out) - 2000; i < len(out); i++ {
+			if out[i] != out[i-1]+1 {
+				t.Fatalf("%s: frame %d at speed 1: %v after %v; want consecutive frames", tt.name, i, out[i], out[i-1])
+			}
+		}
+		if rs.active() {
+			t.Errorf("%s: stage still active at speed 1", tt.name)
+		}
+	}
+}
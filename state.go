@@ -0,0 +1,134 @@
+package playsound
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SoundState — состояние звука.
+//
+// Звук запускается из StateIdle в StatePlaying (или в StateFading при FadeIn),
+// ставится на паузу и снимается с неё, а в конце попадает в StateStopped,
+// из которого выхода нет. StateFading длится, пока идёт плавное изменение
+// громкости, и по его окончании звук переходит в целевое состояние.
+type SoundState int
+
+const (
+	StateIdle    SoundState = iota // Звук создан, но ещё не запущен
+	StatePlaying                   // Звук играет
+	StateFading                    // Идёт плавное нарастание или затухание громкости
+	StatePaused                    // Звук на паузе
+	StateStopped                   // Звук остановлен или доиграл
+)
+
+func (s SoundState) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StatePlaying:
+		return "playing"
+	case StateFading:
+		return "fading"
+	case StatePaused:
+		return "paused"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// TransitionError возвращается, когда звук нельзя перевести в запрошенное
+// состояние: например, поставить на паузу уже приостановленный звук.
+type TransitionError struct {
+	From SoundState // Текущее состояние
+	To   SoundState // Запрошенное состояние
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("sound is %s, cannot switch to %s", e.From, e.To)
+}
+
+// transitions — переходы, которые можно запросить из каждого состояния.
+// Плавность перед паузой или продолжением прерывается любым переходом,
+// кроме плавности к той же цели: так Pause и PlayOn, вызванные подряд,
+// разворачивают громкость с того места, где она сейчас. Затухание перед
+// остановкой прерывается только остановкой.
+var transitions = map[SoundState][]SoundState{
+	StateIdle:    {StatePlaying, StateFading, StateStopped},
+	StatePlaying: {StateFading, StatePaused, StateStopped},
+	StateFading:  {StatePlaying, StateFading, StatePaused, StateStopped},
+	StatePaused:  {StatePlaying, StateFading, StateStopped},
+}
+
+// State возвращает текущее состояние звука.
+func (sc *soundController) State() SoundState {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.state
+}
+
+// switchTo переводит звук в состояние to и под той же блокировкой выполняет
+// apply, чтобы голос и состояние менялись согласованно. Для StateFading
+// fadeTo задаёт состояние после окончания плавности. Любой переход прерывает
+// идущее плавное изменение громкости; возвращается поколение нового перехода.
+func (sc *soundController) switchTo(to, fadeTo SoundState, apply func()) (uint64, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	allowed := slices.Contains(transitions[sc.state], to)
+	if sc.state == StateFading {
+		switch {
+		case sc.fadeTo == StateStopped && to != StateStopped:
+			allowed = false
+		case to == StateFading && fadeTo == sc.fadeTo:
+			allowed = false
+		}
+	}
+	if !allowed {
+		return 0, &TransitionError{From: sc.state, To: to}
+	}
+
+	sc.state, sc.fadeTo = to, fadeTo
+	sc.fadeGen++
+	if apply != nil {
+		apply()
+	}
+	return sc.fadeGen, nil
+}
+
+// endFade завершает плавное изменение громкости поколения gen: выполняет
+// apply и переводит звук в целевое состояние. Возвращает false, если
+// плавность уже прервал другой переход.
+func (sc *soundController) endFade(gen uint64, apply func()) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.state != StateFading || sc.fadeGen != gen {
+		return false
+	}
+	if apply != nil {
+		apply()
+	}
+	sc.state = sc.fadeTo
+	return true
+}
+
+// stopped окончательно переводит звук в StateStopped, прерывая плавность.
+// Возвращает false, если звук уже был остановлен.
+func (sc *soundController) stopped() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.state == StateStopped {
+		return false
+	}
+	sc.state = StateStopped
+	sc.fadeGen++
+	return true
+}
+
+// stopping сообщает, остановлен ли звук или уже затухает перед остановкой:
+// такой звук нельзя вернуть к воспроизведению.
+func (sc *soundController) stopping() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.state == StateStopped || (sc.state == StateFading && sc.fadeTo == StateStopped)
+}
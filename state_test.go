@@ -0,0 +1,299 @@
+package playsound
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransitions(t *testing.T) {
+	tests := []struct {
+		from, to SoundState
+		fadeTo   SoundState
+		ok       bool
+	}{
+		{StateIdle, StatePlaying, 0, true},
+		{StateIdle, StatePaused, 0, false},
+		{StatePlaying, StatePlaying, 0, false},
+		{StatePlaying, StatePaused, 0, true},
+		{StatePaused, StatePaused, 0, false},
+		{StatePaused, StatePlaying, 0, true},
+		{StateFading, StatePaused, StatePlaying, true},   // Пауза во время нарастания
+		{StateFading, StatePaused, StatePaused, true},    // Пауза без плавности во время затухания
+		{StateFading, StatePlaying, StatePaused, true},   // Продолжение отменяет затухание перед паузой
+		{StateFading, StatePlaying, StateStopped, false}, // Затухание перед остановкой не прерывается
+		{StateFading, StateStopped, StatePaused, true},
+		{StateStopped, StatePlaying, 0, false},
+		{StateStopped, StateStopped, 0, false},
+	}
+	for _, tt := range tests {
+		sc := &soundController{state: tt.from, fadeTo: tt.fadeTo}
+		_, err := sc.switchTo(tt.to, 0, nil)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s (to %s) -> %s: err = %v; want ok %v", tt.from, tt.fadeTo, tt.to, err, tt.ok)
+		}
+		var terr *TransitionError
+		if err != nil && (!errors.As(err, &terr) || terr.From != tt.from || terr.To != tt.to) {
+			t.Errorf("%s -> %s: error %v is not a TransitionError", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestFadeGeneration(t *testing.T) {
+	sc := &soundController{state: StatePlaying}
+	gen, _ := sc.switchTo(StateFading, StatePlaying, nil)
+
+	// Повторная плавность к той же цели не разрешена, к другой — разворачивает текущую
+	if _, err := sc.switchTo(StateFading, StatePlaying, nil); err == nil {
+		t.Error("second fade to the same state should fail")
+	}
+	pauseGen, err := sc.switchTo(StateFading, StatePaused, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Пауза прерывает нарастание: его завершение уже ничего не меняет
+	if sc.endFade(gen, nil) || sc.State() != StateFading {
+		t.Errorf("stale endFade changed state to %v", sc.State())
+	}
+	if !sc.endFade(pauseGen, nil) || sc.State() != StatePaused {
+		t.Errorf("endFade: state %v; want paused", sc.State())
+	}
+}
+
+// startTestSound регистрирует зацикленный звук без аудиоустройства
+// и запускает его мониторинг. Голос читает отдельная горутина вместо микшера.
+func startTestSound(t *testing.T, params PlayParams) (chan struct{}, *soundController) {
+	t.Helper()
+	params = validateParams(params)
+	player := newTestVoice(100)
+	player.Pause()
+	player.loop = true
+	player.SetVolume(params.Volume)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	sc := &soundController{cancel: cancel, player: player, params: params, sampleRate: 1000, tracker: player.source, volume: params.Volume}
+	activeMu.Lock()
+	activeSounds[done] = sc
+	activeMu.Unlock()
+	if err := sc.play(TransitionOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	monitorPlayback(ctx, &mockCloser{}, player.source, done, sc)
+
+	go func() {
+		buf := make([]float32, 64)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			player.mixInto(buf, 0)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return done, sc
+}
+
+// Параллельные Pause, PlayOn, SetVolume, Seek и чтение состояния не теряют
+// состояние: в конце голос играет тогда и только тогда, когда звук в StatePlaying.
+func TestControllerStress(t *testing.T) {
+	for _, params := range []PlayParams{{Volume: 0.5}, {Volume: 0.04, FadeIn: true, FadeOut: true}} {
+		done, sc := startTestSound(t, params)
+
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				for range 50 {
+					var err error
+					switch r.Intn(6) {
+					case 0, 1:
+						err = Pause(done)
+					case 2, 3:
+						err = PlayOn(done)
+					case 4:
+						err = SetVolume(done, r.Float64())
+					case 5:
+						ListActive()
+						_, err = GetPosition(done)
+					}
+					var terr *TransitionError
+					if err != nil && !errors.As(err, &terr) {
+						t.Errorf("unexpected error: %v", err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Дожидаемся окончания плавности, начатой последним PlayOn
+		deadline := time.Now().Add(2 * time.Second)
+		for sc.State() == StateFading && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		switch state := sc.State(); state {
+		case StatePlaying, StatePaused:
+			if playing := sc.player.IsPlaying(); playing != (state == StatePlaying) {
+				t.Errorf("params %+v: state %v, voice playing %v", params, state, playing)
+			}
+		default:
+			t.Errorf("params %+v: unexpected final state %v", params, state)
+		}
+
+		Stop(done)
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatal("Таймаут: звук не остановился")
+		}
+		if sc.State() != StateStopped {
+			t.Errorf("state after Stop = %v", sc.State())
+		}
+		if err := PlayOn(done); err == nil {
+			t.Error("PlayOn() after Stop should fail")
+		}
+	}
+}
+
+// Stop во время затухания перед паузой прерывает паузу
+func TestStopDuringPauseFade(t *testing.T) {
+	done, sc := startTestSound(t, PlayParams{Volume: 0.5, FadeOut: true})
+
+	paused := make(chan error, 1)
+	go func() { paused <- PauseWithOptions(done, TransitionOptions{Wait: true}) }()
+	for sc.State() != StateFading {
+		time.Sleep(time.Millisecond)
+	}
+	Stop(done)
+
+	var terr *TransitionError
+	if err := <-paused; !errors.As(err, &terr) || terr.To != StatePaused {
+		t.Errorf("Pause() = %v; want interrupted pause error", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: звук не остановился")
+	}
+}
+
+// PlayOn во время затухания перед паузой разворачивает громкость, не дожидаясь
+// паузы, а SetVolume во время нарастания меняет конечную громкость
+func TestPauseResumeBackToBack(t *testing.T) {
+	done, sc := startTestSound(t, PlayParams{Volume: 0.5, FadeIn: true, FadeOut: true, FadeInTime: 200 * time.Millisecond, FadeOutTime: 200 * time.Millisecond})
+	defer Stop(done)
+	if err := PlayOnWithOptions(done, TransitionOptions{Wait: true}); err == nil {
+		t.Error("PlayOn() during fade-in should fail")
+	}
+	for sc.State() != StatePlaying {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := Pause(done); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Pause() blocked for %v", elapsed)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if v := sc.player.Volume(); v <= 0 || v >= 0.5 {
+		t.Errorf("volume in the middle of fade-out = %v", v)
+	}
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- PlayOnWithOptions(done, TransitionOptions{Wait: true}) }()
+	for {
+		sc.mu.Lock()
+		target := sc.fadeTo
+		sc.mu.Unlock()
+		if target == StatePlaying {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := SetVolume(done, 0.3); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-resumed; err != nil {
+		t.Fatalf("PlayOn() = %v", err)
+	}
+	if sc.State() != StatePlaying || !sc.player.IsPlaying() || sc.player.Volume() != 0.3 {
+		t.Errorf("after resume: state %v, playing %v, volume %v", sc.State(), sc.player.IsPlaying(), sc.player.Volume())
+	}
+
+	// Мгновенная пауза не ждёт и не плавает
+	if err := PauseWithOptions(done, TransitionOptions{NoFade: true}); err != nil || sc.player.IsPlaying() {
+		t.Errorf("PauseWithOptions(NoFade): err %v, playing %v", err, sc.player.IsPlaying())
+	}
+	if v, _ := GetVolume(done); v != 0.3 {
+		t.Errorf("GetVolume() = %v; want 0.3", v)
+	}
+}
+
+// Длительность затухания при остановке выбирается при вызове, а не при запуске
+func TestStopWithOptions(t *testing.T) {
+	if err := StopWithOptions(make(chan struct{}), 0, false); err == nil {
+		t.Error("StopWithOptions() of unknown sound should fail")
+	}
+
+	done, sc := startTestSound(t, PlayParams{Volume: 0.5})
+	start := time.Now()
+	if err := StopWithOptions(done, 100*time.Millisecond, false); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("StopWithOptions() without wait blocked for %v", elapsed)
+	}
+	<-done
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("sound stopped after %v; want a 100ms fade", elapsed)
+	}
+	if sc.State() != StateStopped {
+		t.Errorf("state after stop = %v", sc.State())
+	}
+
+	// Остановка без затухания прерывает долгое затухание из параметров звука
+	done, _ = startTestSound(t, PlayParams{Volume: 0.5, FadeOut: true, FadeOutTime: time.Hour})
+	Stop(done)
+	stopped := make(chan struct{})
+	go func() {
+		StopWithOptions(done, 0, true)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: остановка без затухания не прервала затухание")
+	}
+}
+
+// StopAll останавливает только звуки, запущенные до него
+func TestStopAllWithOptions(t *testing.T) {
+	first, _ := startTestSound(t, PlayParams{Volume: 0.5})
+	second, _ := startTestSound(t, PlayParams{Volume: 0.5, FadeOut: true})
+
+	StopAllWithOptions(20*time.Millisecond, true)
+	for _, done := range []chan struct{}{first, second} {
+		select {
+		case <-done:
+		default:
+			t.Error("StopAllWithOptions() with wait returned before the sound stopped")
+		}
+	}
+
+	after, sc := startTestSound(t, PlayParams{Volume: 0.5})
+	defer Stop(after)
+	time.Sleep(20 * time.Millisecond)
+	if sc.State() != StatePlaying {
+		t.Errorf("sound started after StopAll: state %v", sc.State())
+	}
+}
@@ -0,0 +1,256 @@
+package playsound
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// TrackGroup проигрывает несколько дорожек (стемов) синхронно: все они
+// начинаются с одного кадра микшера и управляются общим транспортом —
+// Seek, Pause и PlayOn применяются ко всем дорожкам между порциями шины,
+// поэтому дорожки не расходятся ни на кадр. Громкость и приглушение
+// каждой дорожки меняются независимо и на лету.
+//
+// Дорожки предполагаются одинаковой длины: доигравшая дорожка выбывает
+// из группы и после перемотки назад не возвращается.
+type TrackGroup struct {
+	mu    sync.Mutex
+	stems []*stem
+	done  chan struct{}
+}
+
+// stem — одна дорожка группы.
+type stem struct {
+	done    chan struct{}
+	control *soundController
+	volume  float64 // Собственная громкость дорожки (0..1)
+	muted   bool
+}
+
+// PlayStems запускает дорожки sources синхронно с общими параметрами params.
+// Если хотя бы одна дорожка не открылась, уже запущенные останавливаются.
+// Порядок дорожек в группе совпадает с порядком sources.
+func PlayStems(params PlayParams, sources ...string) (*TrackGroup, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no stems to play")
+	}
+	params = validateParams(params)
+
+	// Дорожки регистрируются с началом «никогда» и выходят на шину вместе,
+	// когда открыты все: чтение файлов не сдвигает их относительно друг друга.
+	g := &TrackGroup{done: make(chan struct{})}
+	voices := make([]*voice, 0, len(sources))
+	for _, source := range sources {
+		done, err := startSound(source, params, func() int64 { return math.MaxInt64 })
+		if err != nil {
+			g.Stop()
+			return nil, fmt.Errorf("stem %s: %v", source, err)
+		}
+		control, ok := getControl(done)
+		if !ok {
+			g.Stop()
+			return nil, fmt.Errorf("stem %s: sound not found", source)
+		}
+		g.stems = append(g.stems, &stem{done: done, control: control, volume: 1})
+		voices = append(voices, control.player)
+	}
+	masterMixer.startTogether(voices)
+
+	go func() {
+		for _, s := range g.stems {
+			<-s.done
+		}
+		close(g.done)
+	}()
+	return g, nil
+}
+
+// Done возвращает канал, который закрывается, когда все дорожки доиграли или остановлены.
+func (g *TrackGroup) Done() <-chan struct{} {
+	return g.done
+}
+
+// Len возвращает число дорожек в группе.
+func (g *TrackGroup) Len() int {
+	return len(g.stems)
+}
+
+// active возвращает дорожки, которые ещё не остановлены.
+func (g *TrackGroup) active() []*stem {
+	var list []*stem
+	for _, s := range g.stems {
+		if s.control.State() != StateStopped {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// Pause ставит все дорожки на паузу на одном кадре. С FadeOut дорожки
+// вместе затихают в фоне и встают на паузу разом, когда затихла последняя;
+// PlayOn во время затухания отменяет паузу. Как и Pause, возвращает
+// *TransitionError, если группа уже на паузе.
+func (g *TrackGroup) Pause() error {
+	stems := g.active()
+	if len(stems) == 0 {
+		return fmt.Errorf("track group finished")
+	}
+
+	if !stems[0].control.params.FadeOut {
+		var err error
+		masterMixer.sync(func() {
+			for _, s := range stems {
+				_, e := s.control.switchTo(StatePaused, 0, s.control.player.Pause)
+				err = cmp.Or(err, e)
+			}
+		})
+		return err
+	}
+
+	// Дорожки, которые перейти не смогли (например, их как раз остановили),
+	// пропускаем, но остальные всё равно доводим до паузы
+	var err error
+	var fading []*stem
+	var gens []uint64
+	for _, s := range stems {
+		gen, e := s.control.switchTo(StateFading, StatePaused, nil)
+		if e != nil {
+			err = cmp.Or(err, e)
+			continue
+		}
+		fading = append(fading, s)
+		gens = append(gens, gen)
+	}
+
+	// Затихшая дорожка переходит в StatePaused, но голос останавливается
+	// только вместе с остальными — если с тех пор не было другого перехода
+	var wg sync.WaitGroup
+	wg.Add(len(fading))
+	for i, s := range fading {
+		s.control.fade(gens[i], s.control.params.FadeOutTime, nil, func(bool) { wg.Done() })
+	}
+	go func() {
+		wg.Wait()
+		masterMixer.sync(func() {
+			for i, s := range fading {
+				s.control.mu.Lock()
+				if s.control.state == StatePaused && s.control.fadeGen == gens[i] {
+					s.control.player.Pause()
+				}
+				s.control.mu.Unlock()
+			}
+		})
+	}()
+	return err
+}
+
+// PlayOn снимает все дорожки с паузы на одном кадре (с FadeIn — с нарастанием).
+func (g *TrackGroup) PlayOn() error {
+	stems := g.active()
+	if len(stems) == 0 {
+		return fmt.Errorf("track group finished")
+	}
+
+	var err error
+	masterMixer.sync(func() {
+		for _, s := range stems {
+			err = cmp.Or(err, s.control.play(TransitionOptions{}))
+		}
+	})
+	return err
+}
+
+// Seek перематывает все дорожки на позицию seconds на одном кадре.
+// Позиция ограничивается началом и концом каждой дорожки.
+func (g *TrackGroup) Seek(seconds float64) error {
+	stems := g.active()
+	if len(stems) == 0 {
+		return fmt.Errorf("track group finished")
+	}
+
+	var err error
+	masterMixer.sync(func() {
+		for _, s := range stems {
+			frame := math.Round(max(seconds, 0) * float64(s.control.sampleRate))
+			_, e := s.control.seekFrame(int64(frame), 0)
+			err = cmp.Or(err, e)
+		}
+	})
+	return err
+}
+
+// Stop останавливает все дорожки.
+func (g *TrackGroup) Stop() {
+	for _, s := range g.stems {
+		Stop(s.done)
+	}
+}
+
+// Position возвращает общую слышимую позицию группы в секундах.
+func (g *TrackGroup) Position() (float64, error) {
+	stems := g.active()
+	if len(stems) == 0 {
+		return 0, fmt.Errorf("track group finished")
+	}
+	c := stems[0].control
+	return bytesToSeconds(c.audiblePos(), c.sampleRate), nil
+}
+
+// Duration возвращает длительность самой длинной дорожки в секундах.
+func (g *TrackGroup) Duration() float64 {
+	var d float64
+	for _, s := range g.stems {
+		d = max(d, bytesToSeconds(s.control.totalBytes, s.control.sampleRate))
+	}
+	return d
+}
+
+// SetVolume меняет общую громкость группы. Громкости дорожек применяются поверх неё.
+func (g *TrackGroup) SetVolume(volume float64) {
+	for _, s := range g.active() {
+		s.control.setVolume(volume)
+	}
+}
+
+// SetStemVolume задаёт громкость дорожки i (0..1).
+func (g *TrackGroup) SetStemVolume(i int, volume float64) error {
+	if volume < 0 || volume > 1 {
+		return fmt.Errorf("volume %v is out of range [0, 1]", volume)
+	}
+	return g.updateStem(i, func(s *stem) { s.volume = volume })
+}
+
+// MuteStem приглушает дорожку i или возвращает ей звук. Приглушённая
+// дорожка продолжает играть беззвучно и остаётся синхронной с остальными.
+func (g *TrackGroup) MuteStem(i int, mute bool) error {
+	return g.updateStem(i, func(s *stem) { s.muted = mute })
+}
+
+// StemVolume возвращает громкость дорожки i и признак приглушения.
+func (g *TrackGroup) StemVolume(i int) (volume float64, muted bool, err error) {
+	if i < 0 || i >= len(g.stems) {
+		return 0, false, fmt.Errorf("stem %d not found", i)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stems[i].volume, g.stems[i].muted, nil
+}
+
+// updateStem меняет настройки дорожки и пересчитывает её усиление.
+func (g *TrackGroup) updateStem(i int, change func(s *stem)) error {
+	if i < 0 || i >= len(g.stems) {
+		return fmt.Errorf("stem %d not found", i)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := g.stems[i]
+	change(s)
+	trim := s.volume
+	if s.muted {
+		trim = 0
+	}
+	s.control.player.setTrim(trim)
+	return nil
+}
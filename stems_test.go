@@ -0,0 +1,108 @@
+package playsound
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// Голоса, отложенные до общего старта, вступают на одном кадре
+func TestStartTogether(t *testing.T) {
+	m := newMixer()
+	m.sampleRate = 1000
+	m.master.limiter = nil
+
+	a, b := constantVoice(1000), constantVoice(1000)
+	a.startAt, b.startAt = math.MaxInt64, math.MaxInt64
+	m.add(a)
+	buf := make([]byte, 100*frameBytes)
+	m.Read(buf)
+	m.add(b)
+	m.Read(buf)
+
+	if a.started >= 0 || b.started >= 0 {
+		t.Fatal("voices should wait for the common start")
+	}
+	m.startTogether([]*voice{a, b})
+	m.Read(buf)
+	if a.started != 200 || b.started != 200 {
+		t.Errorf("started = %d, %d; want 200, 200", a.started, b.started)
+	}
+}
+
+// voiceField читает поле голоса под его блокировкой.
+func voiceField(v *voice, get func(v *voice) float64) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return get(v)
+}
+
+func TestTrackGroup(t *testing.T) {
+	path := writeTestWAV(t, sinePCM(440, 44100, 1), 44100)
+	g, err := PlayStems(PlayParams{Volume: 0.1}, path, path)
+	if err != nil {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", err)
+	}
+	defer g.Stop()
+	if g.Len() != 2 {
+		t.Fatalf("Len() = %d; want 2", g.Len())
+	}
+	a, b := g.stems[0].control, g.stems[1].control
+
+	time.Sleep(100 * time.Millisecond)
+	started := func(v *voice) float64 { return float64(v.started) }
+	if sa, sb := voiceField(a.player, started), voiceField(b.player, started); sa != sb || sa < 0 {
+		t.Errorf("stems started at %v and %v; want the same frame", sa, sb)
+	}
+
+	if err := g.Pause(); err != nil {
+		t.Fatalf("Pause() error: %v", err)
+	}
+	var te *TransitionError
+	if err := g.Pause(); !errors.As(err, &te) {
+		t.Errorf("second Pause() error = %v; want *TransitionError", err)
+	}
+	if err := g.Seek(0.5); err != nil {
+		t.Fatalf("Seek() error: %v", err)
+	}
+	if a.tracker.CurrentPos() != b.tracker.CurrentPos() {
+		t.Errorf("positions differ after Seek: %d != %d", a.tracker.CurrentPos(), b.tracker.CurrentPos())
+	}
+	if err := g.PlayOn(); err != nil {
+		t.Fatalf("PlayOn() error: %v", err)
+	}
+	if a.State() != StatePlaying || b.State() != StatePlaying {
+		t.Errorf("states = %v, %v; want playing", a.State(), b.State())
+	}
+
+	// Громкость и приглушение дорожки меняют только её усиление
+	if err := g.SetStemVolume(0, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MuteStem(1, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := voiceField(a.player, func(v *voice) float64 { return v.trim }); got != 0.5 {
+		t.Errorf("stem 0 trim = %v; want 0.5", got)
+	}
+	if got := voiceField(b.player, func(v *voice) float64 { return v.trim }); got != 0 {
+		t.Errorf("muted stem gain = %v; want 0", got)
+	}
+	if vol, muted, _ := g.StemVolume(1); vol != 1 || !muted {
+		t.Errorf("StemVolume(1) = %v, %v; want 1, true", vol, muted)
+	}
+	if err := g.SetStemVolume(2, 1); err == nil {
+		t.Error("SetStemVolume() with bad index should fail")
+	}
+
+	g.Stop()
+	select {
+	case <-g.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: группа не остановилась")
+	}
+	if err := g.PlayOn(); err == nil {
+		t.Error("PlayOn() after Stop should fail")
+	}
+}
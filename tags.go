@@ -0,0 +1,435 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PictureFrontCover — тип картинки «лицевая сторона обложки» (одинаков в ID3v2 и FLAC).
+const PictureFrontCover = 3
+
+// maxTagChunk ограничивает размер блока метаданных, который читается в память целиком.
+const maxTagChunk = 16 << 20
+
+// Tags — текстовые теги трека под общими для всех форматов именами в нижнем регистре:
+// title, artist, album, albumartist, track, disc, year, genre, composer, comment.
+// Остальные поля (TXXX, комментарии Vorbis) хранятся под своими именами.
+type Tags map[string]string
+
+// Title возвращает название трека.
+func (t Tags) Title() string { return t["title"] }
+
+// Artist возвращает исполнителя.
+func (t Tags) Artist() string { return t["artist"] }
+
+// Album возвращает название альбома.
+func (t Tags) Album() string { return t["album"] }
+
+// Track возвращает номер трека (0, если не указан); запись вида "3/12" тоже поддерживается.
+func (t Tags) Track() int {
+	value, _, _ := strings.Cut(t["track"], "/")
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
+
+// merge дополняет теги значениями из other, не перезаписывая уже найденные.
+func (t Tags) merge(other Tags) {
+	for k, v := range other {
+		if _, ok := t[k]; !ok && v != "" {
+			t[k] = v
+		}
+	}
+}
+
+// Picture — встроенная картинка, например обложка альбома.
+type Picture struct {
+	MIMEType    string // Например, "image/jpeg"
+	Type        byte   // Назначение картинки по ID3v2/FLAC; 3 — лицевая сторона обложки
+	Description string
+	Data        []byte
+}
+
+// sniffFormat определяет формат по сигнатуре в начале потока: "mp3", "wav", "flac", "ogg"
+// или пустую строку, если формат не распознан. Тег ID3v2 перед данными пропускается,
+// поэтому он не мешает распознать MP3 (или FLAC с ID3). Поток возвращается в начало.
+func sniffFormat(rs io.ReadSeeker) string {
+	defer rs.Seek(0, io.SeekStart)
+
+	var offset int64
+	if tag, _ := readID3v2(rs); tag != nil {
+		offset = tag.size
+	}
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return ""
+	}
+
+	head := make([]byte, 12)
+	n, _ := io.ReadFull(rs, head)
+	head = head[:n]
+
+	switch {
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return "wav"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "flac"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "ogg"
+	}
+	if _, ok := parseMP3Header(head); ok {
+		return "mp3"
+	}
+	if offset > 0 {
+		return "mp3" // После ID3v2 может идти заполнение: кадр найдёт сам декодер
+	}
+	return ""
+}
+
+// readMetadata читает теги и обложку из тегов ID3v1/ID3v2 (MP3), чанков LIST/INFO
+// и id3 (WAV) или комментариев Vorbis (FLAC). Поток возвращается в начало.
+func readMetadata(rs io.ReadSeeker) (Tags, *Picture) {
+	defer rs.Seek(0, io.SeekStart)
+
+	tags := Tags{}
+	var cover *Picture
+
+	switch sniffFormat(rs) {
+	case "wav":
+		cover = readRIFFTags(rs, tags)
+	case "flac":
+		_, cover = readFLAC(rs, tags)
+	default:
+		tag, _ := readID3v2(rs)
+		tags.merge(tag.textFields())
+		cover = tag.picture()
+		tags.merge(readID3v1(rs))
+	}
+	if g, ok := tags["genre"]; ok {
+		tags["genre"] = resolveGenre(g)
+	}
+	return tags, cover
+}
+
+// readID3v1 читает 128-байтный тег ID3v1 (v1.1) в конце файла.
+func readID3v1(rs io.ReadSeeker) Tags {
+	tags := Tags{}
+	if _, err := rs.Seek(-128, io.SeekEnd); err != nil {
+		return tags
+	}
+	b := make([]byte, 128)
+	if _, err := io.ReadFull(rs, b); err != nil || string(b[:3]) != "TAG" {
+		return tags
+	}
+
+	field := func(f []byte) string {
+		if i := bytes.IndexByte(f, 0); i >= 0 {
+			f = f[:i]
+		}
+		return strings.TrimSpace(decodeID3Text(0, f))
+	}
+	tags["title"] = field(b[3:33])
+	tags["artist"] = field(b[33:63])
+	tags["album"] = field(b[63:93])
+	tags["year"] = field(b[93:97])
+	tags["comment"] = field(b[97:127])
+	// ID3v1.1: нулевой 29-й байт комментария означает, что в 30-м записан номер трека
+	if b[125] == 0 && b[126] != 0 {
+		tags["comment"] = field(b[97:125])
+		tags["track"] = strconv.Itoa(int(b[126]))
+	}
+	if int(b[127]) < len(id3v1Genres) {
+		tags["genre"] = id3v1Genres[b[127]]
+	}
+	for k, v := range tags {
+		if v == "" {
+			delete(tags, k)
+		}
+	}
+	return tags
+}
+
+// riffInfoFields сопоставляет поля LIST/INFO с общими именами тегов.
+var riffInfoFields = map[string]string{
+	"INAM": "title",
+	"IART": "artist",
+	"IPRD": "album",
+	"ITRK": "track",
+	"IPRT": "track",
+	"ICRD": "year",
+	"IGNR": "genre",
+	"ICMT": "comment",
+	"ICMP": "composer",
+}
+
+// readRIFFTags обходит чанки WAV: LIST/INFO даёт текстовые поля, а чанк id3
+// (так теги пишут многие редакторы) — теги ID3v2 с обложкой.
+func readRIFFTags(rs io.ReadSeeker, tags Tags) *Picture {
+	var cover *Picture
+	if _, err := rs.Seek(12, io.SeekStart); err != nil {
+		return nil
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(rs, header); err != nil {
+			return cover
+		}
+		id := string(header[:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		next := size + size%2 // Чанки выравниваются на чётную границу
+
+		if (id == "LIST" || id == "id3 " || id == "ID3 ") && size <= maxTagChunk {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(rs, data); err != nil {
+				return cover
+			}
+			switch {
+			case id == "LIST" && bytes.HasPrefix(data, []byte("INFO")):
+				tags.merge(parseRIFFInfo(data[4:]))
+			case id != "LIST":
+				if tag, _ := readID3v2(bytes.NewReader(data)); tag != nil {
+					tags.merge(tag.textFields())
+					if cover == nil {
+						cover = tag.picture()
+					}
+				}
+			}
+			next -= size
+		}
+		if _, err := rs.Seek(next, io.SeekCurrent); err != nil {
+			return cover
+		}
+	}
+}
+
+// parseRIFFInfo разбирает подчанки списка INFO.
+func parseRIFFInfo(b []byte) Tags {
+	tags := Tags{}
+	for len(b) >= 8 {
+		id := string(b[:4])
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if size > len(b) {
+			break
+		}
+		value := strings.TrimSpace(strings.TrimRight(string(b[:size]), "\x00"))
+		if name, ok := riffInfoFields[id]; ok && value != "" {
+			tags[name] = value
+		}
+		b = b[min(size+size%2, len(b)):]
+	}
+	return tags
+}
+
+// flacInfo — параметры потока из блока STREAMINFO.
+type flacInfo struct {
+	sampleRate int
+	channels   int
+	samples    int64 // Количество кадров (0 — неизвестно)
+}
+
+// vorbisFields сопоставляет имена комментариев Vorbis с общими именами тегов.
+var vorbisFields = map[string]string{
+	"TRACKNUMBER": "track",
+	"DISCNUMBER":  "disc",
+	"DATE":        "year",
+	"DESCRIPTION": "comment",
+}
+
+// readFLAC читает блоки метаданных FLAC: STREAMINFO, VORBIS_COMMENT и PICTURE.
+func readFLAC(rs io.ReadSeeker, tags Tags) (*flacInfo, *Picture) {
+	var offset int64
+	if tag, _ := readID3v2(rs); tag != nil {
+		offset = tag.size
+	}
+	if _, err := rs.Seek(offset+4, io.SeekStart); err != nil { // Пропускаем "fLaC"
+		return nil, nil
+	}
+
+	var info *flacInfo
+	var cover *Picture
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(rs, header); err != nil {
+			return info, cover
+		}
+		last := header[0]&0x80 != 0
+		kind := header[0] & 0x7f
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if kind == 0 || kind == 4 || kind == 6 {
+			if size > maxTagChunk {
+				return info, cover
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(rs, data); err != nil {
+				return info, cover
+			}
+			switch kind {
+			case 0:
+				info = parseFLACStreamInfo(data)
+			case 4:
+				tags.merge(parseVorbisComments(data))
+			case 6:
+				if pic := parseFLACPicture(data); pic != nil && (cover == nil || pic.Type == PictureFrontCover) {
+					cover = pic
+				}
+			}
+		} else if _, err := rs.Seek(size, io.SeekCurrent); err != nil {
+			return info, cover
+		}
+		if last {
+			return info, cover
+		}
+	}
+}
+
+// parseFLACStreamInfo разбирает STREAMINFO: частота (20 бит), каналы (3 бита),
+// разрядность (5 бит) и число кадров (36 бит) начинаются с 10-го байта.
+func parseFLACStreamInfo(b []byte) *flacInfo {
+	if len(b) < 18 {
+		return nil
+	}
+	v := binary.BigEndian.Uint64(b[10:18])
+	return &flacInfo{
+		sampleRate: int(v >> 44),
+		channels:   int(v>>41&0x7) + 1,
+		samples:    int64(v & (1<<36 - 1)),
+	}
+}
+
+// parseVorbisComments разбирает комментарии Vorbis: строка поставщика
+// и список строк вида "ИМЯ=значение"; длины записаны в little-endian.
+func parseVorbisComments(b []byte) Tags {
+	tags := Tags{}
+	next := func() (string, bool) {
+		if len(b) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		b = b[4:]
+		if n > len(b) {
+			return "", false
+		}
+		s := string(b[:n])
+		b = b[n:]
+		return s, true
+	}
+
+	if _, ok := next(); !ok { // Поставщик
+		return tags
+	}
+	if len(b) < 4 {
+		return tags
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			break
+		}
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok || value == "" {
+			continue
+		}
+		key = strings.ToUpper(key)
+		name, known := vorbisFields[key]
+		if !known {
+			name = strings.ToLower(key)
+		}
+		// Повторяющиеся поля (например, несколько исполнителей) объединяем
+		if prev, ok := tags[name]; ok {
+			value = prev + "; " + value
+		}
+		tags[name] = value
+	}
+	return tags
+}
+
+// parseFLACPicture разбирает блок PICTURE; все числа в нём big-endian.
+func parseFLACPicture(b []byte) *Picture {
+	u32 := func() (int, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		v := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return v, true
+	}
+	str := func() (string, bool) {
+		n, ok := u32()
+		if !ok || n > len(b) {
+			return "", false
+		}
+		s := string(b[:n])
+		b = b[n:]
+		return s, true
+	}
+
+	kind, ok := u32()
+	if !ok {
+		return nil
+	}
+	pic := &Picture{Type: byte(kind)}
+	if pic.MIMEType, ok = str(); !ok {
+		return nil
+	}
+	if pic.Description, ok = str(); !ok {
+		return nil
+	}
+	if len(b) < 16 { // Ширина, высота, глубина цвета, размер палитры
+		return nil
+	}
+	b = b[16:]
+	data, ok := str()
+	if !ok {
+		return nil
+	}
+	pic.Data = []byte(data)
+	return pic
+}
+
+// resolveGenre заменяет числовые ссылки на жанры ID3v1 вида "(17)", "17" или "(17)Rock" названиями.
+func resolveGenre(g string) string {
+	g = strings.TrimSpace(g)
+	if strings.HasPrefix(g, "(") {
+		if end := strings.IndexByte(g, ')'); end > 0 {
+			if rest := strings.TrimSpace(g[end+1:]); rest != "" {
+				return rest // Уточнённое название после ссылки
+			}
+			g = g[1:end]
+		}
+	}
+	if n, err := strconv.Atoi(g); err == nil && n >= 0 && n < len(id3v1Genres) {
+		return id3v1Genres[n]
+	}
+	return g
+}
+
+// id3v1Genres — стандартный список жанров ID3v1.
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap",
+	"Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks",
+	"Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock",
+	"Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle",
+	"Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi",
+	"Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+}
+
+// GetTags возвращает теги и обложку играющего звука (обложка может быть nil).
+func GetTags(done chan struct{}) (Tags, *Picture, error) {
+	control, ok := getControl(done)
+	if !ok {
+		return nil, nil, fmt.Errorf("sound not found")
+	}
+	return control.tags, control.cover, nil
+}
@@ -0,0 +1,201 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+)
+
+// riffChunk собирает чанк RIFF с выравниванием на чётную границу.
+func riffChunk(id string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+// writeTaggedWAV записывает WAV с чанком LIST/INFO после данных.
+func writeTaggedWAV(t *testing.T, pcm []byte, rate int, info map[string]string) string {
+	t.Helper()
+	var list bytes.Buffer
+	list.WriteString("INFO")
+	for id, v := range info {
+		list.Write(riffChunk(id, append([]byte(v), 0)))
+	}
+
+	var fmtChunk bytes.Buffer
+	for _, v := range []any{uint16(1), uint16(2), uint32(rate), uint32(rate * 4), uint16(4), uint16(16)} {
+		binary.Write(&fmtChunk, binary.LittleEndian, v)
+	}
+
+	body := append([]byte("WAVE"), riffChunk("fmt ", fmtChunk.Bytes())...)
+	body = append(body, riffChunk("data", pcm)...)
+	body = append(body, riffChunk("LIST", list.Bytes())...)
+
+	path := t.TempDir() + "/tagged.wav"
+	if err := os.WriteFile(path, riffChunk("RIFF", body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWAVInfoTags(t *testing.T) {
+	path := writeTaggedWAV(t, sinePCM(440, 8000, 0.5), 8000, map[string]string{
+		"INAM": "Title", "IART": "Artist", "IPRD": "Album", "ITRK": "7", "IGNR": "Rock",
+	})
+
+	info, err := Probe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "wav" || info.Tags.Title() != "Title" || info.Tags.Artist() != "Artist" ||
+		info.Tags.Album() != "Album" || info.Tags.Track() != 7 || info.Tags["genre"] != "Rock" {
+		t.Errorf("Probe() = %+v", info)
+	}
+
+	if otoCtx == nil {
+		return
+	}
+	done, err := PlaySoundWithParams(path, PlayParams{Volume: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, cover, err := GetTags(done)
+	if err != nil || tags.Title() != "Title" || cover != nil {
+		t.Errorf("GetTags() = %v, %v, %v", tags, cover, err)
+	}
+	Stop(done)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: звук не завершился")
+	}
+}
+
+func TestID3Tags(t *testing.T) {
+	apic := "\x00image/png\x00\x03\x00PNGDATA"
+	tag := id3v23(
+		[2]string{"TIT2", "\x01\xff\xfeS\x00o\x00n\x00g\x00"},
+		[2]string{"TCON", "\x00(17)"},
+		[2]string{"APIC", "\x00image/jpeg\x00\x04back\x00BACK"},
+		[2]string{"APIC", apic},
+	)
+	v1 := make([]byte, 128)
+	copy(v1, "TAG")
+	copy(v1[3:], "Old title")
+	copy(v1[33:], "V1 Artist")
+	v1[126] = 5 // Номер трека ID3v1.1
+	v1[127] = 8 // Jazz
+
+	file := append(append(tag, 0xff, 0xfb, 0x90, 0x00), v1...)
+	tags, cover := readMetadata(bytes.NewReader(file))
+
+	if tags.Title() != "Song" {
+		t.Errorf("title = %q; want ID3v2 value to win", tags.Title())
+	}
+	if tags.Artist() != "V1 Artist" || tags.Track() != 5 {
+		t.Errorf("ID3v1 fields not merged: %v", tags)
+	}
+	if tags["genre"] != "Rock" {
+		t.Errorf("genre = %q; want Rock", tags["genre"])
+	}
+	if cover == nil || cover.MIMEType != "image/png" || cover.Type != PictureFrontCover || string(cover.Data) != "PNGDATA" {
+		t.Errorf("cover = %+v; want front PNG", cover)
+	}
+}
+
+func TestFLACProbe(t *testing.T) {
+	block := func(kind byte, last bool, data []byte) []byte {
+		if last {
+			kind |= 0x80
+		}
+		return append([]byte{kind, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+	}
+
+	// STREAMINFO: 44100 Гц, 2 канала, 16 бит, 88200 кадров
+	info := make([]byte, 34)
+	v := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36 | 88200
+	binary.BigEndian.PutUint64(info[10:], v)
+
+	var comments bytes.Buffer
+	le := func(s string) {
+		binary.Write(&comments, binary.LittleEndian, uint32(len(s)))
+		comments.WriteString(s)
+	}
+	le("vendor")
+	binary.Write(&comments, binary.LittleEndian, uint32(3))
+	le("TITLE=Flac song")
+	le("TRACKNUMBER=2")
+	le("ARTIST=Someone")
+
+	var pic bytes.Buffer
+	be := func(v uint32) { binary.Write(&pic, binary.BigEndian, v) }
+	be(3)
+	be(10)
+	pic.WriteString("image/jpeg")
+	be(0)
+	pic.Write(make([]byte, 16))
+	be(4)
+	pic.WriteString("JPEG")
+
+	file := []byte("fLaC")
+	file = append(file, block(0, false, info)...)
+	file = append(file, block(4, false, comments.Bytes())...)
+	file = append(file, block(6, true, pic.Bytes())...)
+
+	path := t.TempDir() + "/test.flac"
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Probe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Format != "flac" || p.SampleRate != 44100 || p.Channels != 2 || p.Duration != 2 {
+		t.Errorf("Probe() = %+v", p)
+	}
+	if p.Tags.Title() != "Flac song" || p.Tags.Track() != 2 || p.Tags.Artist() != "Someone" {
+		t.Errorf("tags = %v", p.Tags)
+	}
+	if p.Cover == nil || string(p.Cover.Data) != "JPEG" {
+		t.Errorf("cover = %+v", p.Cover)
+	}
+
+	if _, err := PlaySoundWithParams(path, PlayParams{}); err == nil {
+		t.Error("FLAC playback should be rejected")
+	}
+}
+
+// Тест распознавания формата: PCM с синхрословом MP3 остаётся WAV, тег ID3 не мешает MP3
+func TestSniffFormat(t *testing.T) {
+	pcm := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 1024)
+	path := writeTestWAV(t, pcm, 44100)
+	wavFile, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"WAV with sync-like PCM", wavFile, "wav"},
+		{"MP3 with ID3v2", append(id3v23([2]string{"TIT2", "\x00x"}), 0xff, 0xfb, 0x90, 0x00), "mp3"},
+		{"Bare MP3", []byte{0xff, 0xfb, 0x90, 0x00, 0, 0}, "mp3"},
+		{"Ogg", []byte("OggS\x00\x02"), "ogg"},
+		{"Unknown", []byte("hello world!"), ""},
+	}
+	for _, tt := range tests {
+		if got := sniffFormat(bytes.NewReader(tt.data)); got != tt.want {
+			t.Errorf("%s: sniffFormat() = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package playsound
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleAt запускает звук в момент at и сразу возвращает его канал done.
+// Файл открывается и декодер готовится заранее, а на шину микшера звук
+// вступает с точностью до кадра. Момент пересчитывается в кадр с учётом
+// буферов и задержки устройства (см. SetOutputLatency); если он уже прошёл,
+// звук начинается немедленно.
+//
+// До начала звук считается играющим, его позиция — 0, а нарастание FadeIn
+// начинается вместе со звуком. Отменить запланированный звук можно обычным
+// Stop. Лимиты голосов действуют сразу, а политика повторного запуска
+// источника (SourceLimit.Policy) к запланированным звукам не применяется.
+func ScheduleAt(filePath string, params PlayParams, at time.Time) (chan struct{}, error) {
+	return startSound(filePath, validateParams(params), func() int64 {
+		return masterMixer.frameAt(at)
+	})
+}
+
+// ScheduleAfter запускает звук через delay после начала звука ref и сразу
+// возвращает его канал done. Оба звука стоят на одном таймлайне микшера,
+// поэтому интервал между ними выдерживается с точностью до кадра — в том
+// числе если ref сам запланирован и ещё не начался.
+func ScheduleAfter(filePath string, params PlayParams, ref chan struct{}, delay time.Duration) (chan struct{}, error) {
+	control, ok := getControl(ref)
+	if !ok {
+		return nil, fmt.Errorf("reference sound not found")
+	}
+	if delay < 0 {
+		return nil, fmt.Errorf("invalid delay %v", delay)
+	}
+
+	return startSound(filePath, validateParams(params), func() int64 {
+		start := masterMixer.startFrame(control.player)
+		return start + int64(delay.Seconds()*float64(masterMixer.rate()))
+	})
+}
@@ -0,0 +1,148 @@
+package playsound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// constantVoice создаёт играющий голос из frames кадров с постоянной амплитудой.
+func constantVoice(frames int) *voice {
+	pcm := make([]byte, frames*frameBytes)
+	for i := 0; i < len(pcm); i += 2 {
+		binary.LittleEndian.PutUint16(pcm[i:], 16384)
+	}
+	v := newVoice(&trackingStream{decodedStream: &pcmStream{bytes.NewReader(pcm), 1000}})
+	v.Play()
+	return v
+}
+
+// Запланированный голос вступает ровно с кадра startAt, даже посреди порции микшера
+func TestScheduledVoiceStart(t *testing.T) {
+	m := newMixer()
+	m.sampleRate = 1000
+	m.master.limiter = nil
+
+	v := constantVoice(1000)
+	v.startAt = 150
+	m.add(v)
+
+	if got := m.startFrame(v); got != 150 {
+		t.Errorf("startFrame() before start = %d; want 150", got)
+	}
+
+	buf := make([]byte, 100*frameBytes)
+	first := -1
+	for frame := 0; frame < 300 && first < 0; frame += 100 {
+		m.Read(buf)
+		for i := 0; i < 100; i++ {
+			if int16(binary.LittleEndian.Uint16(buf[i*frameBytes:])) != 0 {
+				first = frame + i
+				break
+			}
+		}
+	}
+	if first != 150 {
+		t.Errorf("first audible frame = %d; want 150", first)
+	}
+	if got := m.startFrame(v); got != 150 {
+		t.Errorf("startFrame() after start = %d; want 150", got)
+	}
+
+	// Голос без расписания начинается со следующей порции
+	now := newVoice(nil)
+	if got := m.startFrame(now); got != m.frames {
+		t.Errorf("startFrame() of new voice = %d; want %d", got, m.frames)
+	}
+}
+
+func TestFrameAt(t *testing.T) {
+	SetOutputLatency(0)
+	defer SetOutputLatency(defaultOutputLatency)
+
+	m := newMixer()
+	m.sampleRate = 1000
+	m.frames = 5000
+
+	if got := m.frameAt(time.Now().Add(time.Second)); got < 5990 || got > 6000 {
+		t.Errorf("frameAt(+1s) = %d; want ~6000", got)
+	}
+	// Прошедший момент заменяется ближайшим несмешанным кадром
+	if got := m.frameAt(time.Now().Add(-time.Second)); got != 5000 {
+		t.Errorf("frameAt(-1s) = %d; want 5000", got)
+	}
+}
+
+// Звук, запланированный после другого, отстаёт от него ровно на заданное число кадров
+func TestScheduleAfter(t *testing.T) {
+	path := writeTestWAV(t, sinePCM(440, 44100, 0.3), 44100)
+	first, err := ScheduleAt(path, PlayParams{Volume: 0.1}, time.Now().Add(100*time.Millisecond))
+	if err != nil {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", err)
+	}
+	second, err := ScheduleAfter(path, PlayParams{Volume: 0.1}, first, 250*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstCtl, _ := getControl(first)
+	secondCtl, _ := getControl(second)
+
+	if _, err := ScheduleAfter(path, PlayParams{}, make(chan struct{}), 0); err == nil {
+		t.Error("ScheduleAfter() with unknown reference should fail")
+	}
+
+	<-first
+	<-second
+	rate := int64(masterMixer.rate())
+	if diff := secondCtl.player.started - firstCtl.player.started; diff != rate/4 {
+		t.Errorf("start difference = %d frames; want %d", diff, rate/4)
+	}
+}
+
+// Запланированный звук можно отменить до начала обычным Stop
+func TestScheduledStop(t *testing.T) {
+	path := writeTestWAV(t, sinePCM(440, 44100, 0.3), 44100)
+	done, err := ScheduleAt(path, PlayParams{}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", err)
+	}
+	control, _ := getControl(done)
+	Stop(done)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Таймаут: запланированный звук не отменился")
+	}
+	if control.player.started >= 0 {
+		t.Error("cancelled sound should never start")
+	}
+}
+
+// Нарастание FadeIn у запланированного звука начинается вместе со звуком
+func TestScheduledFadeIn(t *testing.T) {
+	path := writeTestWAV(t, sinePCM(440, 44100, 2), 44100)
+	done, err := ScheduleAt(path, PlayParams{Volume: 1, FadeIn: true, FadeInTime: time.Second}, time.Now().Add(time.Second))
+	if err != nil {
+		t.Skipf("Пропуск: нет аудиоустройства (%v)", err)
+	}
+	defer StopWithOptions(done, 0, true)
+	control, _ := getControl(done)
+
+	time.Sleep(500 * time.Millisecond)
+	if v := control.player.Volume(); v != 0 || control.State() != StateFading {
+		t.Errorf("before start: volume %v, state %v; want 0, fading", v, control.State())
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for voiceField(control.player, func(v *voice) float64 { return float64(v.started) }) < 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Таймаут: запланированный звук не начался")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if v := control.player.Volume(); v > 0.3 {
+		t.Errorf("volume right after start = %v; want the fade-in to begin at the start", v)
+	}
+}
@@ -2,6 +2,7 @@ package playsound
 
 import (
 	"fmt"
+	"math"
 )
 
 // secondsToBytes рассчитывает размер аудио-данных в байтах на основе длительности.
@@ -19,6 +20,16 @@ func bytesToSeconds(b int64, sampleRate int) float64 {
 	return float64(b) / float64(sampleRate * 4)
 }
 
+// streamLength возвращает размер декодированного потока в байтах, если декодер его знает.
+func streamLength(stream decodedStream) int64 {
+	if l, ok := stream.(interface{ Length() int64 }); ok {
+		return l.Length()
+	} else if l, ok := stream.(interface{ Length() int }); ok {
+		return int64(l.Length())
+	}
+	return 0
+}
+
 // validateParams проверяет и корректирует параметры перед запуском.
 func validateParams(p PlayParams) PlayParams {
 	// Если громкость не указана, ставим 1.0 (100%)
@@ -28,6 +39,26 @@ func validateParams(p PlayParams) PlayParams {
 		p.Volume = 0
 	}
 
+	// Скорость 0 означает обычную, остальное приводим к допустимому диапазону
+	if p.Speed == 0 {
+		p.Speed = 1
+	} else {
+		p.Speed = math.Min(math.Max(p.Speed, minSpeed), maxSpeed)
+	}
+	p.Pitch = math.Min(math.Max(p.Pitch, -maxPitch), maxPitch)
+	p.Pan = math.Min(math.Max(p.Pan, -1), 1)
+
+	if p.Normalize && p.TargetLUFS == 0 {
+		p.TargetLUFS = defaultTargetLUFS
+	}
+
+	if p.FadeOutTime <= 0 {
+		p.FadeOutTime = defaultFadeOutTime
+	}
+	if p.FadeInTime <= 0 {
+		p.FadeInTime = defaultFadeInTime
+	}
+
 	// Позиция не может быть отрицательной
 	if p.Position < 0 {
 		p.Position = 0